@@ -4,6 +4,7 @@ go 1.18
 
 require (
 	github.com/oasisprotocol/oasis-core/go v0.2202.11
+	golang.org/x/net v0.13.0
 	google.golang.org/grpc v1.57.0
 )
 
@@ -45,7 +46,6 @@ require (
 	go.uber.org/multierr v1.11.0 // indirect
 	go.uber.org/zap v1.24.0 // indirect
 	golang.org/x/crypto v0.12.0 // indirect
-	golang.org/x/net v0.13.0 // indirect
 	golang.org/x/sys v0.11.0 // indirect
 	golang.org/x/text v0.12.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20230525234030-28d5490b6b19 // indirect
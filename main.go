@@ -4,36 +4,73 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
 	oasisGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
 	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+	keymanager "github.com/oasisprotocol/oasis-core/go/keymanager/api"
 	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
 	roothash "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	runtimeApi "github.com/oasisprotocol/oasis-core/go/runtime/client/api"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/proto"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
+// -cacert, repeatable
+type caFiles []string
+
+func (c *caFiles) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *caFiles) Set(s string) error {
+	*c = append(*c, s)
+	return nil
+}
+
 func main() {
-	// Fix TLS missing
-	//certfile, _ := ioutil.ReadFile("cert.pem")
+	var caCerts caFiles
+	flag.Var(&caCerts, "cacert", "PEM file of CA certificates to trust besides the system's, for gateways with a private CA; repeatable")
+	sni := flag.String("sni", "", "TLS server name to present and verify instead of the host, e.g. to reach one machine by IP behind an SNI-routing proxy")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: grpc-test [-cacert ca.pem]... [-sni name] host:port")
+		os.Exit(2)
+	}
+
 	certPool, err := x509.SystemCertPool()
 	if err != nil || certPool == nil {
 		certPool = x509.NewCertPool()
 	}
-	//certPool.AppendCertsFromPEM(certfile)
+	for _, path := range caCerts {
+		certfile, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Print("cacert error: ")
+			fmt.Println(err)
+			return
+		}
+		if !certPool.AppendCertsFromPEM(certfile) {
+			fmt.Println("cacert error: no certificates in", path)
+			return
+		}
+	}
 	creds := credentials.NewTLS(&tls.Config{
 		//MinVersion: tls.VersionTLS12,
-		RootCAs: certPool,
-		//ServerName: "grpc.cobalt.archive.emerald.oasiscloud.io",
+		RootCAs:    certPool,
+		ServerName: *sni,
 	})
 
 	certPool, err = x509.SystemCertPool()
 	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
-	conn, err := oasisGrpc.Dial(os.Args[1], dialOpts...)
+	conn, err := oasisGrpc.Dial(flag.Arg(0), dialOpts...)
 	if err != nil {
 		fmt.Print("Dial error: ")
 		fmt.Println(err)
@@ -44,8 +81,20 @@ func main() {
 	consensusClient := consensus.NewConsensusClient(conn)
 	roothashClient := roothash.NewRootHashClient(conn)
 	registryClient := registry.NewRegistryClient(conn)
+	keymanagerClient := keymanager.NewKeymanagerClient(conn)
+	runtimeClient := runtimeApi.NewRuntimeClient(conn)
 	ctx := context.Background()
 
+	// gateways answer this even when the node behind them is down; it speaks
+	// protobuf rather than the cbor oasisGrpc.Dial forces
+	health, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.ForceCodec(encoding.GetCodec(proto.Name)))
+	if err != nil {
+		fmt.Print("Health error: ")
+		fmt.Println(err)
+	} else {
+		fmt.Println("Health: ", health.Status)
+	}
+
 	epoch, err := beaconClient.GetBaseEpoch(ctx)
 	if err != nil {
 		fmt.Print("GetBaseEpoch error: ")
@@ -58,6 +107,14 @@ func main() {
 	height := block.Height
 	fmt.Println("LatestHeight: ", height)
 
+	status, err := consensusClient.GetStatus(ctx)
+	if err != nil {
+		fmt.Print("GetStatus error: ")
+		fmt.Println(err)
+	} else {
+		fmt.Println("LastRetainedHeight: ", status.LastRetainedHeight)
+	}
+
 	runtimes, err := registryClient.GetRuntimes(ctx, &registry.GetRuntimesQuery{Height: height, IncludeSuspended: false})
 	if err != nil {
 		fmt.Print("GetRuntimes error: ")
@@ -76,6 +133,25 @@ func main() {
 		}
 		t := time.Unix(int64(runtimeState.CurrentBlock.Header.Timestamp), 0)
 		fmt.Println("\t", t)
+
+		retained, err := runtimeClient.GetLastRetainedBlock(ctx, runtime.ID)
+		if err != nil {
+			fmt.Print("\tGetLastRetainedBlock error: ")
+			fmt.Println(err)
+		} else {
+			fmt.Println("\t\tLastRetainedRound:", retained.Header.Round, "LatestRound:", runtimeState.CurrentBlock.Header.Round)
+		}
+
+		if runtime.KeyManager == nil {
+			continue
+		}
+		km, err := keymanagerClient.GetStatus(ctx, &registry.NamespaceQuery{ID: *runtime.KeyManager, Height: height})
+		if err != nil {
+			fmt.Print("\tGetStatus (keymanager) error: ")
+			fmt.Println(err)
+			continue
+		}
+		fmt.Println("\t\tKeyManager:", km.ID.Hex(), "Initialized:", km.IsInitialized, "Secure:", km.IsSecure, "Nodes:", len(km.Nodes))
 	}
 
 	chainContext, err := consensusClient.GetChainContext(ctx)
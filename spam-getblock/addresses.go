@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/oasisprotocol/oasis-sdk/client-sdk/go/types"
+)
+
+// state for PickAddressIndex
+var (
+	addressNext  uint64
+	addressMutex sync.Mutex
+	addressZipf  *rand.Zipf
+)
+
+// the comma separated -address list followed by the lines of
+// -addresses-file; blank lines and lines starting with # are skipped
+func AddressList() ([]string, error) {
+	var list []string
+	if ADDRESS != "" {
+		for _, s := range strings.Split(ADDRESS, ",") {
+			list = append(list, strings.TrimSpace(s))
+		}
+	}
+	if ADDRESSES_FILE == "" {
+		return list, nil
+	}
+	f, err := os.Open(ADDRESSES_FILE)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		list = append(list, text)
+	}
+	return list, scanner.Err()
+}
+
+// index into an address list of length n according to -address-sampling
+func PickAddressIndex(n int) int {
+	switch ADDRESS_SAMPLING {
+	case "round-robin":
+		return int((atomic.AddUint64(&addressNext, 1) - 1) % uint64(n))
+	case "zipf":
+		addressMutex.Lock()
+		defer addressMutex.Unlock()
+		if addressZipf == nil {
+			addressZipf = rand.NewZipf(rand.New(rand.NewSource(SEED)), ZIPF_S, 1, uint64(n-1))
+		}
+		return int(addressZipf.Uint64())
+	}
+	return rand.Intn(n)
+}
+
+// the zipf distribution is sized to the list it first picked from, so it
+// and the round-robin position start over when a Setup loads a new list
+func resetAddressSampling() {
+	addressMutex.Lock()
+	defer addressMutex.Unlock()
+	addressZipf = nil
+	atomic.StoreUint64(&addressNext, 0)
+}
+
+func CheckAddressSampling() error {
+	switch ADDRESS_SAMPLING {
+	case "random", "round-robin":
+		return nil
+	case "zipf":
+		if ZIPF_S <= 1 {
+			return fmt.Errorf("-zipf-s must be > 1")
+		}
+		return nil
+	}
+	return fmt.Errorf("bad -address-sampling '%s', expected random, round-robin or zipf", ADDRESS_SAMPLING)
+}
+
+// set by LoadAccountAddresses, once per runtime with -all-runtimes
+var accountAddresses []types.Address
+
+// parses the address list for runtime account queries: oasis1 addresses,
+// or 0x eth addresses on evm runtimes
+func LoadAccountAddresses(ctx context.Context) error {
+	list, err := AddressList()
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return fmt.Errorf("account queries need -address or -addresses-file")
+	}
+	accountAddresses = nil
+	resetAddressSampling()
+	for _, s := range list {
+		if strings.HasPrefix(s, "0x") {
+			eth, err := hex.DecodeString(s[2:])
+			if err != nil || len(eth) != 20 {
+				return fmt.Errorf("bad eth address '%s'", s)
+			}
+			accountAddresses = append(accountAddresses, types.NewAddressFromEth(eth))
+			continue
+		}
+		var address types.Address
+		if err := address.UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("bad address '%s': %w", s, err)
+		}
+		accountAddresses = append(accountAddresses, address)
+	}
+	return nil
+}
+
+func PickAccountAddress() types.Address {
+	return accountAddresses[PickAddressIndex(len(accountAddresses))]
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	oasisGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+// active compute runtimes in the registry at the latest height
+func DiscoverRuntimes(ctx context.Context) ([]common.Namespace, error) {
+	conn, err := oasisGrpc.Dial(URL, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	runtimes, err := registry.NewRegistryClient(conn).GetRuntimes(ctx, &registry.GetRuntimesQuery{Height: consensus.HeightLatest, IncludeSuspended: false})
+	if err != nil {
+		return nil, err
+	}
+	var ids []common.Namespace
+	for _, rt := range runtimes {
+		if rt.Kind == registry.KindCompute {
+			ids = append(ids, rt.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no active compute runtimes")
+	}
+	return ids, nil
+}
+
+// splits -n between the discovered runtimes and runs the call against each
+// in turn, with its own retained window and setup; returns number of
+// requests and of failed ones, counting a skipped runtime's share as failed
+func RunAllRuntimes(ctx context.Context, call Call) (num_requests, num_errors int) {
+	ids, err := DiscoverRuntimes(ctx)
+	if err != nil {
+		fmt.Println("Runtime discovery error:", err)
+		return NUM_REQUESTS, NUM_REQUESTS
+	}
+
+	total := NUM_REQUESTS
+	minRound, maxRound := MIN_ROUND, MAX_ROUND
+	type result struct {
+		name      string
+		requests  int
+		errors    int
+		timeTaken time.Duration
+	}
+	var results []result
+	for i, id := range ids {
+		RUNTIME = id.Hex()
+		RUNTIME_NAME = ""
+		if err := ParseRuntime(); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		name := RUNTIME_NAME
+		if name == "" {
+			name = RUNTIME
+		}
+		// the remainder goes to the first runtimes
+		NUM_REQUESTS = total / len(ids)
+		if i < total%len(ids) {
+			NUM_REQUESTS++
+		}
+		fmt.Println("Runtime:", name, "Requests:", NUM_REQUESTS)
+
+		MIN_ROUND, MAX_ROUND = minRound, maxRound
+		if err := ClampToRetained(ctx, call); err != nil {
+			fmt.Println("Retained window error, skipping runtime:", err)
+			results = append(results, result{name, NUM_REQUESTS, NUM_REQUESTS, 0})
+			continue
+		}
+		if call.Setup != nil {
+			if err := call.Setup(ctx); err != nil {
+				fmt.Println("Setup error, skipping runtime:", err)
+				results = append(results, result{name, NUM_REQUESTS, NUM_REQUESTS, 0})
+				continue
+			}
+		}
+
+		start := time.Now()
+		errors := CallSimultaneous(ctx, call.F, call.Params)
+		results = append(results, result{name, NUM_REQUESTS, errors, time.Since(start)})
+	}
+	NUM_REQUESTS = total
+
+	for _, r := range results {
+		rate := float32(0)
+		if r.timeTaken > 0 {
+			rate = float32(r.requests) / float32(r.timeTaken.Seconds())
+		}
+		fmt.Printf("%s: Errors: %d / %d, Time: %s, Rate: %.2f /s\n", r.name, r.errors, r.requests, r.timeTaken, rate)
+		num_requests += r.requests
+		num_errors += r.errors
+	}
+	return num_requests, num_errors
+}
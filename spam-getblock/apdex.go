@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// requests within -apdex-threshold are satisfied, within four times it
+// tolerating, slower or failed ones frustrated
+type Apdex struct {
+	Threshold  float64 `json:"threshold_ms"`
+	Satisfied  int     `json:"satisfied"`
+	Tolerating int     `json:"tolerating"`
+	Frustrated int     `json:"frustrated"`
+	Score      float64 `json:"score"`
+}
+
+// by call; only touched by the goroutine collecting statuses
+var apdex = map[string]*Apdex{}
+
+func RecordApdex(s *ThreadStatus) {
+	if APDEX_THRESHOLD <= 0 {
+		return
+	}
+	a, ok := apdex[CALL]
+	if !ok {
+		a = &Apdex{Threshold: ms(APDEX_THRESHOLD)}
+		apdex[CALL] = a
+	}
+	total := s.times.Total()
+	switch {
+	case s.err != nil || total > 4*APDEX_THRESHOLD:
+		a.Frustrated++
+	case total > APDEX_THRESHOLD:
+		a.Tolerating++
+	default:
+		a.Satisfied++
+	}
+	n := a.Satisfied + a.Tolerating + a.Frustrated
+	a.Score = (float64(a.Satisfied) + float64(a.Tolerating)/2) / float64(n)
+}
+
+func PrintApdex() {
+	calls := make([]string, 0, len(apdex))
+	for call := range apdex {
+		calls = append(calls, call)
+	}
+	sort.Strings(calls)
+	for _, call := range calls {
+		a := apdex[call]
+		fmt.Printf("Apdex %s (%s): %.2f, satisfied: %d, tolerating: %d, frustrated: %d\n",
+			call, APDEX_THRESHOLD, a.Score, a.Satisfied, a.Tolerating, a.Frustrated)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// a fraction given as 0.01 or 1%, unset until given
+type fraction struct {
+	v   float64
+	set bool
+}
+
+func percent(v float64) string {
+	return strconv.FormatFloat(v*100, 'g', 4, 64) + "%"
+}
+
+func (f *fraction) String() string {
+	if !f.set {
+		return ""
+	}
+	return percent(f.v)
+}
+
+func (f *fraction) Set(s string) error {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(s, "%") {
+		v /= 100
+	}
+	*f = fraction{v, true}
+	return nil
+}
+
+// -assert-* limits; zero or unset means not asserted
+var (
+	ASSERT_P50        time.Duration
+	ASSERT_P99        time.Duration
+	ASSERT_ERROR_RATE fraction
+	ASSERT_MIN_RATE   float64
+)
+
+type Assertion struct {
+	Name   string `json:"name"`
+	Limit  string `json:"limit"`
+	Actual string `json:"actual"`
+	Passed bool   `json:"passed"`
+}
+
+// evaluates every -assert-* flag that was given against the run
+func CheckAssertions(num_requests, num_errors int, time_taken time.Duration) []Assertion {
+	var results []Assertion
+	if ASSERT_P50 > 0 {
+		p50 := latencies.Total.Percentile(0.5)
+		results = append(results, Assertion{"p50", ASSERT_P50.String(), p50.String(), p50 <= ASSERT_P50})
+	}
+	if ASSERT_P99 > 0 {
+		p99 := latencies.Total.Percentile(0.99)
+		results = append(results, Assertion{"p99", ASSERT_P99.String(), p99.String(), p99 <= ASSERT_P99})
+	}
+	if ASSERT_ERROR_RATE.set {
+		rate := 0.0
+		if num_requests != 0 {
+			rate = float64(num_errors) / float64(num_requests)
+		}
+		results = append(results, Assertion{"error rate", ASSERT_ERROR_RATE.String(), percent(rate), rate <= ASSERT_ERROR_RATE.v})
+	}
+	if ASSERT_MIN_RATE > 0 {
+		rate := float64(num_requests) / time_taken.Seconds()
+		results = append(results, Assertion{"rate", fmt.Sprintf("%g/s", ASSERT_MIN_RATE), fmt.Sprintf("%.1f/s", rate), rate >= ASSERT_MIN_RATE})
+	}
+	return results
+}
+
+func AssertionsFailed(results []Assertion) bool {
+	for _, a := range results {
+		if !a.Passed {
+			return true
+		}
+	}
+	return false
+}
+
+// lists every assertion, failures marked, under a header that says whether
+// the run passed
+func PrintAssertions(results []Assertion) {
+	if len(results) == 0 {
+		return
+	}
+	if AssertionsFailed(results) {
+		fmt.Println("ASSERTIONS FAILED:")
+	} else {
+		fmt.Println("Assertions passed:")
+	}
+	for _, a := range results {
+		mark := "ok  "
+		if !a.Passed {
+			mark = "FAIL"
+		}
+		fmt.Printf("  %s %s: %s (limit %s)\n", mark, a.Name, a.Actual, a.Limit)
+	}
+}
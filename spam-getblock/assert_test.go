@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFractionSet(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"0.01", 0.01, true},
+		{"1%", 0.01, true},
+		{"0.5%", 0.005, true},
+		{"0", 0, true},
+		{"100%", 1, true},
+		{"", 0, false},
+		{"%", 0, false},
+		{"one", 0, false},
+	} {
+		t.Run(tt.in, func(t *testing.T) {
+			var f fraction
+			err := f.Set(tt.in)
+			if (err == nil) != tt.ok {
+				t.Fatalf("error %v", err)
+			}
+			if f.v != tt.want || f.set != tt.ok {
+				t.Fatalf("got %g, set %v", f.v, f.set)
+			}
+		})
+	}
+}
+
+func TestCheckAssertions(t *testing.T) {
+	defer func(p50, p99 time.Duration, errorRate fraction, minRate float64, l *LatencyReport) {
+		ASSERT_P50, ASSERT_P99, ASSERT_ERROR_RATE, ASSERT_MIN_RATE, latencies = p50, p99, errorRate, minRate, l
+	}(ASSERT_P50, ASSERT_P99, ASSERT_ERROR_RATE, ASSERT_MIN_RATE, latencies)
+	latencies = NewLatencyReport()
+	for i := 1; i <= 100; i++ {
+		latencies.Total.Add(time.Duration(i) * time.Millisecond)
+	}
+
+	for _, tt := range []struct {
+		name       string
+		p50, p99   time.Duration
+		errorRate  string
+		minRate    float64
+		requests   int
+		errors     int
+		timeTaken  time.Duration
+		wantPassed []bool
+	}{
+		{name: "none", requests: 100, timeTaken: time.Second},
+		{name: "p50 ok", p50: 60 * time.Millisecond, requests: 100, timeTaken: time.Second, wantPassed: []bool{true}},
+		{name: "p50 over", p50: 40 * time.Millisecond, requests: 100, timeTaken: time.Second, wantPassed: []bool{false}},
+		{name: "p99 over", p99: 50 * time.Millisecond, requests: 100, timeTaken: time.Second, wantPassed: []bool{false}},
+		{name: "error rate ok", errorRate: "1%", requests: 1000, errors: 10, timeTaken: time.Second, wantPassed: []bool{true}},
+		{name: "error rate over", errorRate: "1%", requests: 100, errors: 2, timeTaken: time.Second, wantPassed: []bool{false}},
+		{name: "error rate no requests", errorRate: "0", timeTaken: time.Second, wantPassed: []bool{true}},
+		{name: "rate ok", minRate: 50, requests: 100, timeTaken: time.Second, wantPassed: []bool{true}},
+		{name: "rate under", minRate: 50, requests: 100, timeTaken: 4 * time.Second, wantPassed: []bool{false}},
+		{name: "all", p50: time.Second, p99: time.Second, errorRate: "0.1", minRate: 1, requests: 100, errors: 50, timeTaken: time.Second,
+			wantPassed: []bool{true, true, false, true}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ASSERT_P50, ASSERT_P99, ASSERT_MIN_RATE = tt.p50, tt.p99, tt.minRate
+			ASSERT_ERROR_RATE = fraction{}
+			if tt.errorRate != "" {
+				if err := ASSERT_ERROR_RATE.Set(tt.errorRate); err != nil {
+					t.Fatal(err)
+				}
+			}
+			results := CheckAssertions(tt.requests, tt.errors, tt.timeTaken)
+			if len(results) != len(tt.wantPassed) {
+				t.Fatalf("%d assertions, expected %d", len(results), len(tt.wantPassed))
+			}
+			failed := false
+			for i, a := range results {
+				if a.Passed != tt.wantPassed[i] {
+					t.Errorf("%s: passed %v with %s against %s", a.Name, a.Passed, a.Actual, a.Limit)
+				}
+				failed = failed || !tt.wantPassed[i]
+			}
+			if AssertionsFailed(results) != failed {
+				t.Errorf("AssertionsFailed %v", !failed)
+			}
+		})
+	}
+}
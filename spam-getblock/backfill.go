@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Connect plus every phase
+func (t *ApiTimes) Total() time.Duration {
+	total := t.Connect
+	for _, phase := range t.Phases() {
+		total += phase.Duration
+	}
+	return total
+}
+
+// calls every height in [from, to] in order with at most WINDOW in flight,
+// like a nexus backfill; returns number of failed requests
+func CallRange(ctx context.Context,
+	call_f func(context.Context, uint64) ThreadStatus,
+	from, to uint64,
+) (num_errors int) {
+	ch := make(chan ThreadStatus, WINDOW)
+	go func() {
+		wg := sync.WaitGroup{}
+		window := make(chan struct{}, WINDOW)
+		for height := from; height <= to; height++ {
+			window <- struct{}{}
+			wg.Add(1)
+			go func(height uint64) {
+				defer wg.Done()
+				subctx, cancel := context.WithTimeout(WithStatusSlot(ctx), TIMEOUT)
+				ch <- track(func() ThreadStatus { return call_f(subctx, height) })
+				cancel()
+				<-window
+			}(height)
+		}
+		wg.Wait()
+		close(ch)
+	}()
+
+	for status := range ch {
+		if CollectStatus(status) {
+			num_errors += 1
+		}
+	}
+	return num_errors
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/stats"
+)
+
+// received payload of one grpc method within a request, and how long its
+// rpcs took from start to end
+type RpcSize struct {
+	Bytes    int64
+	Duration time.Duration
+}
+
+type rpcMethodKey struct{}
+
+type rpcStatusKey struct{}
+
+// when the rpc started waiting for a transport, just before stats.Begin,
+// and whether it got a stream
+type rpcState struct {
+	begin time.Time
+	open  bool
+}
+
+type rpcStateKey struct{}
+
+// counts the payload bytes received into the ThreadStatus of the request
+// making each rpc, in total and per method, and keeps the response
+// metadata. a per-request connection has one with status set; a shared one
+// finds the status in the rpc's context, see Connect
+type byteCounter struct {
+	status *ThreadStatus
+	shared bool
+	conn   string // local->remote address, for shared connections
+	mu     sync.Mutex
+}
+
+func (c *byteCounter) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	method := info.FullMethodName[strings.LastIndex(info.FullMethodName, "/")+1:]
+	ctx = context.WithValue(ctx, rpcMethodKey{}, method)
+	ctx = context.WithValue(ctx, rpcStateKey{}, &rpcState{begin: time.Now()})
+	if c.shared {
+		if slot, ok := ctx.Value(statusSlotKey{}).(*statusSlot); ok && slot.status != nil {
+			c.mu.Lock()
+			slot.status.conn = c.conn
+			c.mu.Unlock()
+			ctx = context.WithValue(ctx, rpcStatusKey{}, slot.status)
+		}
+	}
+	return ctx
+}
+
+func (c *byteCounter) statusFor(ctx context.Context) *ThreadStatus {
+	if !c.shared {
+		return c.status
+	}
+	status, _ := ctx.Value(rpcStatusKey{}).(*ThreadStatus)
+	return status
+}
+
+func (c *byteCounter) rpc(ctx context.Context, status *ThreadStatus) *RpcSize {
+	method, _ := ctx.Value(rpcMethodKey{}).(string)
+	if status.rpcs == nil {
+		status.rpcs = map[string]*RpcSize{}
+	}
+	size, ok := status.rpcs[method]
+	if !ok {
+		size = &RpcSize{}
+		status.rpcs[method] = size
+	}
+	return size
+}
+
+func (c *byteCounter) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	status := c.statusFor(ctx)
+	if status == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch s := s.(type) {
+	case *stats.Begin:
+		// transparent retries never reached the server, so don't count
+		if n, ok := ctx.Value(rpcAttemptsKey{}).(*int); ok && !s.IsTransparentRetryAttempt {
+			*n++
+			if *n == 1 {
+				status.calls++
+			} else {
+				status.retries++
+			}
+		}
+	case *stats.OutHeader:
+		// headers go out once the rpc has a ready transport
+		if state, ok := ctx.Value(rpcStateKey{}).(*rpcState); ok {
+			status.queued += time.Since(state.begin)
+			state.open = true
+			streamOpened()
+		}
+		// with -lb-policy one connection has a transport per backend, so
+		// only the header says which one the rpc went out on
+		if s.RemoteAddr != nil {
+			status.peer = s.RemoteAddr.String()
+			status.conn = fmt.Sprintf("%s->%s", s.LocalAddr, s.RemoteAddr)
+		}
+		// only a fresh connection has setup to time
+		if !c.shared && status.connTimes.Ready == 0 {
+			status.connTimes.Ready = time.Since(status.start)
+		}
+	case *stats.InPayload:
+		atomic.AddInt64(&status.bytes, int64(s.WireLength))
+		atomic.AddInt64(&status.rawBytes, int64(s.Length))
+		c.rpc(ctx, status).Bytes += int64(s.WireLength)
+	case *stats.InHeader:
+		captureMetadata(status, s.Header)
+	case *stats.InTrailer:
+		captureMetadata(status, s.Trailer)
+	case *stats.End:
+		c.rpc(ctx, status).Duration += s.EndTime.Sub(s.BeginTime)
+		if state, ok := ctx.Value(rpcStateKey{}).(*rpcState); ok && state.open {
+			state.open = false
+			streamClosed()
+		}
+	}
+}
+
+func (c *byteCounter) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	c.mu.Lock()
+	c.conn = fmt.Sprintf("%s->%s", info.LocalAddr, info.RemoteAddr)
+	if c.status != nil {
+		c.status.conn = c.conn
+	}
+	c.mu.Unlock()
+	return ctx
+}
+
+// response sizes of one grpc method over the run
+type MethodBandwidth struct {
+	sizes    []int64
+	Bytes    int64
+	Duration time.Duration // summed over rpcs, so Mbps is per rpc not per run
+}
+
+func (m *MethodBandwidth) Percentile(q float64) int64 {
+	if len(m.sizes) == 0 {
+		return 0
+	}
+	return m.sizes[int(q*float64(len(m.sizes)-1))]
+}
+
+func (m *MethodBandwidth) Mbps() float64 {
+	if m.Duration <= 0 {
+		return 0
+	}
+	return float64(m.Bytes) * 8 / m.Duration.Seconds() / 1e6
+}
+
+type BandwidthSummary struct {
+	Bytes int64   `json:"bytes"`
+	P50   int64   `json:"p50_bytes"`
+	P99   int64   `json:"p99_bytes"`
+	Max   int64   `json:"max_bytes"`
+	Mbps  float64 `json:"mbps"`
+}
+
+// every request's response sizes by method; only touched by the goroutine
+// collecting statuses until Bandwidth sorts them at the end
+var (
+	bandwidth      = map[string]*MethodBandwidth{}
+	bandwidthOrder []string
+	bandwidthTotal int64
+)
+
+func RecordBandwidth(s *ThreadStatus) {
+	bandwidthTotal += s.bytes
+	for method, size := range s.rpcs {
+		m, ok := bandwidth[method]
+		if !ok {
+			m = &MethodBandwidth{}
+			bandwidth[method] = m
+			bandwidthOrder = append(bandwidthOrder, method)
+		}
+		m.sizes = append(m.sizes, size.Bytes)
+		m.Bytes += size.Bytes
+		m.Duration += size.Duration
+	}
+}
+
+func Bandwidth() map[string]BandwidthSummary {
+	summary := map[string]BandwidthSummary{}
+	for method, m := range bandwidth {
+		sort.Slice(m.sizes, func(i, j int) bool { return m.sizes[i] < m.sizes[j] })
+		summary[method] = BandwidthSummary{m.Bytes, m.Percentile(0.5), m.Percentile(0.99), m.Percentile(1), m.Mbps()}
+	}
+	return summary
+}
+
+// per method sizes and throughput while in the rpc, then the whole run's
+// throughput over wall time
+func PrintBandwidth(time_taken time.Duration) {
+	if bandwidthTotal == 0 {
+		return
+	}
+	summary := Bandwidth()
+	for _, method := range bandwidthOrder {
+		b := summary[method]
+		fmt.Printf("Bytes %s: total: %d, p50: %d, p99: %d, max: %d, %.1f Mbps\n", method, b.Bytes, b.P50, b.P99, b.Max, b.Mbps)
+	}
+	fmt.Printf("Bytes: %d, %.1f Mbps\n", bandwidthTotal, float64(bandwidthTotal)*8/time_taken.Seconds()/1e6)
+}
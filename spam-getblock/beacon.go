@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+)
+
+// (height, epoch) pairs seen by GetEpoch, checked by CheckEpochsMonotonic
+var (
+	epochsMu sync.Mutex
+	epochs   = map[uint64]beacon.EpochTime{}
+)
+
+func GetEpoch(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	start := time.Now()
+	epoch, err := beacon.NewBeaconClient(conn).GetEpoch(ctx, int64(height))
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	epochsMu.Lock()
+	epochs[height] = epoch
+	epochsMu.Unlock()
+	status.msg = fmt.Sprintf("Height: %d, Epoch: %d", height, epoch)
+	return status
+}
+
+// epochs must never decrease as height increases
+func CheckEpochsMonotonic() error {
+	heights := make([]uint64, 0, len(epochs))
+	for height := range epochs {
+		heights = append(heights, height)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+	for i := 1; i < len(heights); i++ {
+		prev, cur := heights[i-1], heights[i]
+		if epochs[cur] < epochs[prev] {
+			return fmt.Errorf("epoch went backwards: height %d has epoch %d, height %d has epoch %d",
+				prev, epochs[prev], cur, epochs[cur])
+		}
+	}
+	fmt.Println("Epochs monotonic across", len(heights), "heights")
+	return nil
+}
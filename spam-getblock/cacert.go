@@ -0,0 +1,47 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// -cacert path.pem, repeatable; CAs trusted besides the system's, for
+// gateways with a private CA
+type caCertList []string
+
+var CA_CERTS caCertList
+
+func (c *caCertList) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *caCertList) Set(s string) error {
+	*c = append(*c, s)
+	return nil
+}
+
+// the system roots plus every -cacert
+func rootCAs() (*x509.CertPool, error) {
+	certPool, err := x509.SystemCertPool()
+	if err != nil || certPool == nil {
+		certPool = x509.NewCertPool()
+	}
+	for _, path := range CA_CERTS {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("bad -cacert: %w", err)
+		}
+		if !certPool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("bad -cacert %s: no PEM certificates in it", path)
+		}
+	}
+	return certPool, nil
+}
+
+// fails early on an unreadable -cacert, before SetupGrpcOpts needs it
+func CheckCaCerts() error {
+	_, err := rootCAs()
+	return err
+}
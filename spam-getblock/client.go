@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// how often the client's own usage is sampled
+const CLIENT_SAMPLE_INTERVAL = time.Second
+
+// above this share of the available cores the client is likely the
+// bottleneck rather than the server
+const CLIENT_CPU_WARNING = 0.9
+
+// the load generator's own resource usage over the run
+type ClientUsage struct {
+	Cores         int     `json:"cores"` // GOMAXPROCS
+	CPUSeconds    float64 `json:"cpu_seconds"`
+	CPUMean       float64 `json:"cpu_mean"` // cores busy, averaged over the run
+	CPUPeak       float64 `json:"cpu_peak"` // over one sample interval
+	RSSPeakMB     float64 `json:"rss_peak_mb"`
+	Goroutines    int     `json:"goroutines_peak"`
+	NumGC         uint32  `json:"num_gc"`
+	GCPauseMs     float64 `json:"gc_pause_total_ms"`
+	GCPauseMaxMs  float64 `json:"gc_pause_max_ms"`
+	GCCPUFraction float64 `json:"gc_cpu_fraction"`
+	Warning       string  `json:"warning,omitempty"`
+}
+
+var clientUsage struct {
+	sync.Mutex
+	ClientUsage
+	start    time.Time
+	startCPU time.Duration
+	startGC  uint32
+	startGCP uint64
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+func cpuTime() time.Duration {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+}
+
+// resident set size from /proc, 0 where there is none
+func rssBytes() int64 {
+	b, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, _ := strconv.ParseInt(fields[1], 10, 64)
+	return pages * int64(os.Getpagesize())
+}
+
+func StartClientUsage() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	clientUsage.start = time.Now()
+	clientUsage.startCPU = cpuTime()
+	clientUsage.startGC = ms.NumGC
+	clientUsage.startGCP = ms.PauseTotalNs
+	clientUsage.Cores = runtime.GOMAXPROCS(0)
+	clientUsage.stop = make(chan struct{})
+	clientUsage.stopped = make(chan struct{})
+	go func() {
+		defer close(clientUsage.stopped)
+		ticker := time.NewTicker(CLIENT_SAMPLE_INTERVAL)
+		defer ticker.Stop()
+		lastCPU, last := clientUsage.startCPU, clientUsage.start
+		for {
+			select {
+			case now := <-ticker.C:
+				cpu := cpuTime()
+				sampleClientUsage(float64(cpu-lastCPU) / float64(now.Sub(last)))
+				lastCPU, last = cpu, now
+			case <-clientUsage.stop:
+				return
+			}
+		}
+	}()
+}
+
+func sampleClientUsage(cores float64) {
+	clientUsage.Lock()
+	defer clientUsage.Unlock()
+	if cores > clientUsage.CPUPeak {
+		clientUsage.CPUPeak = cores
+	}
+	if rss := float64(rssBytes()) / 1e6; rss > clientUsage.RSSPeakMB {
+		clientUsage.RSSPeakMB = rss
+	}
+	if n := runtime.NumGoroutine(); n > clientUsage.Goroutines {
+		clientUsage.Goroutines = n
+	}
+}
+
+// stops sampling and fills in the totals and the warning
+func StopClientUsage() *ClientUsage {
+	close(clientUsage.stop)
+	<-clientUsage.stopped
+	sampleClientUsage(0)
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	clientUsage.Lock()
+	defer clientUsage.Unlock()
+	u := &clientUsage.ClientUsage
+	cpu := cpuTime() - clientUsage.startCPU
+	u.CPUSeconds = cpu.Seconds()
+	u.CPUMean = float64(cpu) / float64(time.Since(clientUsage.start))
+	u.NumGC = ms.NumGC - clientUsage.startGC
+	u.GCPauseMs = float64(ms.PauseTotalNs-clientUsage.startGCP) / 1e6
+	for i := uint32(0); i < u.NumGC && i < uint32(len(ms.PauseNs)); i++ {
+		pause := float64(ms.PauseNs[(ms.NumGC-1-i)%uint32(len(ms.PauseNs))]) / 1e6
+		if pause > u.GCPauseMaxMs {
+			u.GCPauseMaxMs = pause
+		}
+	}
+	u.GCCPUFraction = ms.GCCPUFraction
+	if u.CPUPeak >= CLIENT_CPU_WARNING*float64(u.Cores) {
+		u.Warning = fmt.Sprintf("client used %.1f of %d cores at peak; it may be the bottleneck, not the server", u.CPUPeak, u.Cores)
+	}
+	usage := *u
+	return &usage
+}
+
+func (u *ClientUsage) Print() {
+	fmt.Printf("Client: CPU: %.1fs (mean %.2f, peak %.2f of %d cores), RSS peak: %.0fMB, Goroutines peak: %d, GC: %d, pauses %.1fms (max %.1fms), %.1f%% of CPU\n",
+		u.CPUSeconds, u.CPUMean, u.CPUPeak, u.Cores, u.RSSPeakMB, u.Goroutines, u.NumGC, u.GCPauseMs, u.GCPauseMaxMs, 100*u.GCCPUFraction)
+	if u.Warning != "" {
+		fmt.Println("WARNING:", u.Warning)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// the -cert and -key pair, for gateways that want mutual TLS
+var clientCerts []tls.Certificate
+
+func CheckClientCert() error {
+	if CERT_FILE == "" && KEY_FILE == "" {
+		return nil
+	}
+	if CERT_FILE == "" || KEY_FILE == "" {
+		return fmt.Errorf("-cert and -key go together")
+	}
+	if PLAINTEXT {
+		return fmt.Errorf("-cert needs TLS, not -plaintext")
+	}
+	cert, err := tls.LoadX509KeyPair(CERT_FILE, KEY_FILE)
+	if err != nil {
+		return fmt.Errorf("bad -cert or -key: %w", err)
+	}
+	clientCerts = []tls.Certificate{cert}
+	return nil
+}
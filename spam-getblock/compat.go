@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	oasisGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	roothash "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	runtime "github.com/oasisprotocol/oasis-core/go/runtime/client/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// cobalt (21.x) archive nodes don't have GetTransactionsWithResults; they
+// serve the raw transactions through GetTxs, keyed by the block's IO root
+var methodCobaltGetTxs = oasisGrpc.NewServiceName("RuntimeClient").NewMethod("GetTxs", cobaltGetTxsRequest{})
+
+type cobaltGetTxsRequest struct {
+	RuntimeID common.Namespace `json:"runtime_id"`
+	Round     uint64           `json:"round"`
+	IORoot    hash.Hash        `json:"io_root"`
+}
+
+// resolves -api-version auto by checking whether the node knows
+// GetTransactionsWithResults; without probe, for calls that don't care, it
+// only validates the flag and leaves auto unresolved
+func DetectApiVersion(ctx context.Context, probe bool) error {
+	switch API_VERSION {
+	case "damask", "cobalt":
+		return nil
+	case "auto":
+	default:
+		return fmt.Errorf("bad -api-version '%s', expected auto, damask or cobalt", API_VERSION)
+	}
+	if !probe {
+		return nil
+	}
+
+	conn, err := oasisGrpc.Dial(URL, dialOpts...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = runtime.NewRuntimeClient(conn).GetTransactionsWithResults(ctx, &runtime.GetTransactionsRequest{
+		RuntimeID: RUNTIME_ID,
+		Round:     roothash.RoundLatest,
+	})
+	switch status.Code(err) {
+	case codes.OK:
+		API_VERSION = "damask"
+	case codes.Unimplemented:
+		API_VERSION = "cobalt"
+	default:
+		return err
+	}
+	return nil
+}
+
+// GetSapphireRound for cobalt nodes; without results there is nothing for
+// nexus to parse
+func GetCobaltRound(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	client := runtime.NewRuntimeClient(conn)
+	start := time.Now()
+	block, err := client.GetBlock(ctx, &runtime.GetBlockRequest{
+		RuntimeID: RUNTIME_ID,
+		Round:     height,
+	})
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.times.GetBlock = time.Since(start)
+
+	start = time.Now()
+	var txs [][]byte
+	err = conn.Invoke(ctx, methodCobaltGetTxs.FullName(), &cobaltGetTxsRequest{
+		RuntimeID: RUNTIME_ID,
+		Round:     height,
+		IORoot:    block.Header.IORoot,
+	}, &txs)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.times.GetTransactions = time.Since(start)
+
+	start = time.Now()
+	events, err := client.GetEvents(ctx, &runtime.GetEventsRequest{
+		RuntimeID: RUNTIME_ID,
+		Round:     height,
+	})
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.times.GetEvents = time.Since(start)
+
+	status.msg = fmt.Sprintf("Round: %d, NumTransactions: %d, NumEvents: %d, Hash: %s", height, len(txs), len(events), block.Header.EncodedHash())
+	return status
+}
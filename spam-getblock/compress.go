@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+func CheckCompress() error {
+	switch COMPRESS {
+	case "", "none", gzip.Name:
+		return nil
+	}
+	return fmt.Errorf("bad -compress '%s', expected gzip or none", COMPRESS)
+}
+
+func compressing() bool {
+	return COMPRESS != "" && COMPRESS != "none"
+}
+
+var numCompressPicks uint64
+
+// the compressor for a request, "" for none; with -compress-compare every
+// other request goes uncompressed so both are measured under the same load
+func pickCompressor() string {
+	if !compressing() {
+		return ""
+	}
+	if COMPRESS_COMPARE && atomic.AddUint64(&numCompressPicks, 1)%2 == 0 {
+		return ""
+	}
+	return COMPRESS
+}
+
+// per-request connections compress every call; the server answers with the
+// compressor the request used
+func CompressDialOpts(status *ThreadStatus) []grpc.DialOption {
+	if status.compressor == "" {
+		return nil
+	}
+	return []grpc.DialOption{grpc.WithDefaultCallOptions(grpc.UseCompressor(status.compressor))}
+}
+
+// the same for a shared connection, with the compressor of whichever
+// request makes the call
+func SharedCompressDialOpts() []grpc.DialOption {
+	if !compressing() {
+		return nil
+	}
+	compressor := func(ctx context.Context, opts []grpc.CallOption) []grpc.CallOption {
+		slot, ok := ctx.Value(statusSlotKey{}).(*statusSlot)
+		if !ok || slot.status == nil || slot.status.compressor == "" {
+			return opts
+		}
+		return append(opts, grpc.UseCompressor(slot.status.compressor))
+	}
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(ctx, method, req, reply, cc, compressor(ctx, opts)...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(ctx, desc, cc, method, compressor(ctx, opts)...)
+		}),
+	}
+}
+
+// successful requests with one compressor
+type compressionGroup struct {
+	total    Histogram
+	bytes    int64
+	rawBytes int64
+}
+
+type CompressionSummary struct {
+	Requests int     `json:"requests"`
+	P50      float64 `json:"p50_ms"`
+	P99      float64 `json:"p99_ms"`
+	Bytes    int64   `json:"bytes"`     // on the wire
+	RawBytes int64   `json:"raw_bytes"` // after decompressing
+}
+
+// by compressor, "none" for uncompressed; only touched by the goroutine
+// collecting statuses
+var compression = map[string]*compressionGroup{}
+
+func RecordCompression(s *ThreadStatus) {
+	if s.err != nil || !compressing() {
+		return
+	}
+	name := s.compressor
+	if name == "" {
+		name = "none"
+	}
+	g, ok := compression[name]
+	if !ok {
+		g = &compressionGroup{}
+		compression[name] = g
+	}
+	g.total.Add(s.times.Total())
+	g.bytes += s.bytes
+	g.rawBytes += atomic.LoadInt64(&s.rawBytes)
+}
+
+func Compression() map[string]CompressionSummary {
+	if len(compression) == 0 {
+		return nil
+	}
+	summary := map[string]CompressionSummary{}
+	for name, g := range compression {
+		summary[name] = CompressionSummary{int(g.total.Count), ms(g.total.Percentile(0.5)), ms(g.total.Percentile(0.99)), g.bytes, g.rawBytes}
+	}
+	return summary
+}
+
+func (c CompressionSummary) BytesPerRequest() float64 {
+	if c.Requests == 0 {
+		return 0
+	}
+	return float64(c.Bytes) / float64(c.Requests)
+}
+
+func percentChange(from, to float64) float64 {
+	if from == 0 {
+		return 0
+	}
+	return 100 * (to - from) / from
+}
+
+// each compressor's latency and wire size, how much smaller the payloads
+// got, and with -compress-compare the change against uncompressed
+func PrintCompression() {
+	summary := Compression()
+	names := make([]string, 0, len(summary))
+	for name := range summary {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := summary[name]
+		fmt.Printf("Compression %s: n: %d, p50: %.3gms, p99: %.3gms, bytes/request: %.0f", name, c.Requests, c.P50, c.P99, c.BytesPerRequest())
+		if name != "none" && c.RawBytes != 0 {
+			fmt.Printf(", wire/raw: %.1f%%", 100*float64(c.Bytes)/float64(c.RawBytes))
+		}
+		fmt.Println()
+	}
+	none, ok1 := summary["none"]
+	compressed, ok2 := summary[COMPRESS]
+	if !ok1 || !ok2 {
+		return
+	}
+	fmt.Printf("Compression delta: p50: %+.3gms (%+.1f%%), p99: %+.3gms (%+.1f%%), bytes/request: %+.1f%%\n",
+		compressed.P50-none.P50, percentChange(none.P50, compressed.P50),
+		compressed.P99-none.P99, percentChange(none.P99, compressed.P99),
+		percentChange(none.BytesPerRequest(), compressed.BytesPerRequest()))
+}
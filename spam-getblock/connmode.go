@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	oasisGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	"google.golang.org/grpc"
+)
+
+// where Connect puts the request's status for shared connections' stats
+// handlers to find; CallSimultaneous and CallRange add one to each
+// request's context
+type statusSlotKey struct{}
+
+type statusSlot struct {
+	status *ThreadStatus
+}
+
+func WithStatusSlot(ctx context.Context) context.Context {
+	return context.WithValue(ctx, statusSlotKey{}, &statusSlot{})
+}
+
+func CheckConnMode() error {
+	switch CONN_MODE {
+	case "per-request", "shared":
+		return nil
+	case "pool":
+		if POOL_SIZE < 1 {
+			return fmt.Errorf("bad -pool-size %d", POOL_SIZE)
+		}
+		if POOL_ASSIGN != "round-robin" && POOL_ASSIGN != "least-loaded" {
+			return fmt.Errorf("bad -pool-assign '%s', expected round-robin or least-loaded", POOL_ASSIGN)
+		}
+		return nil
+	}
+	return fmt.Errorf("bad -conn-mode '%s', expected per-request, shared or pool", CONN_MODE)
+}
+
+// one of a pool's connections, with the requests using it right now
+type pooledConnection struct {
+	pool     *connPool
+	conn     *grpc.ClientConn
+	label    string // endpoint#index
+	inFlight int64
+	peak     int64 // most requests multiplexed on it at once
+}
+
+// connections reused across requests, POOL_SIZE per endpoint for pool and
+// one for shared, dialed on first use
+type connPool struct {
+	mu    sync.Mutex
+	conns []*pooledConnection
+	next  int
+}
+
+var (
+	poolsMutex sync.Mutex
+	pools      = map[string]*connPool{}
+	// to find the pool entry again in Release
+	pooledByConn sync.Map
+)
+
+// the next connection by -pool-assign: in turn, or the one with the fewest
+// requests in flight
+func (pool *connPool) pick() int {
+	if POOL_ASSIGN == "least-loaded" {
+		best := 0
+		for i, c := range pool.conns {
+			if c == nil {
+				return i
+			}
+			if c.inFlight < pool.conns[best].inFlight {
+				best = i
+			}
+		}
+		return best
+	}
+	i := pool.next
+	pool.next = (pool.next + 1) % len(pool.conns)
+	return i
+}
+
+func pooledConn(endpoint string) (*grpc.ClientConn, string, error) {
+	size := 1
+	if CONN_MODE == "pool" {
+		size = POOL_SIZE
+	}
+	poolsMutex.Lock()
+	pool, ok := pools[endpoint]
+	if !ok {
+		pool = &connPool{conns: make([]*pooledConnection, size)}
+		pools[endpoint] = pool
+	}
+	poolsMutex.Unlock()
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	i := pool.pick()
+	if pool.conns[i] == nil {
+		opts := append(dialOpts[:len(dialOpts):len(dialOpts)], grpc.WithStatsHandler(&byteCounter{shared: true}))
+		opts = append(opts, SharedTraceDialOpts()...)
+		opts = append(opts, SharedCompressDialOpts()...)
+		opts = append(opts, endpointDialOpts(endpoint)...)
+		conn, err := oasisGrpc.Dial(lbTarget(endpoint), opts...)
+		if err != nil {
+			return nil, "", err
+		}
+		pool.conns[i] = &pooledConnection{pool: pool, conn: conn, label: fmt.Sprintf("%s#%d", endpoint, i)}
+		WatchState(conn, pool.conns[i].label)
+		WatchReconnects(conn, pool.conns[i].label)
+		pooledByConn.Store(conn, pool.conns[i])
+	}
+	c := pool.conns[i]
+	c.inFlight++
+	if c.inFlight > c.peak {
+		c.peak = c.inFlight
+	}
+	return c.conn, c.label, nil
+}
+
+// what the calls defer instead of conn.Close, which would close shared
+// connections under other requests
+func Release(conn *grpc.ClientConn) {
+	if CONN_MODE == "per-request" {
+		conn.Close()
+		return
+	}
+	if v, ok := pooledByConn.Load(conn); ok {
+		c := v.(*pooledConnection)
+		c.pool.mu.Lock()
+		c.inFlight--
+		c.pool.mu.Unlock()
+	}
+}
+
+// closes the shared connections at the end of the run
+func ClosePools() {
+	atomic.StoreInt32(&poolsClosing, 1)
+	poolsMutex.Lock()
+	defer poolsMutex.Unlock()
+	for _, pool := range pools {
+		for _, c := range pool.conns {
+			if c != nil {
+				c.conn.Close()
+			}
+		}
+	}
+}
+
+// per pooled connection; only touched by the goroutine collecting statuses
+var poolConnStats = map[string]*EndpointStats{}
+
+func RecordPoolConn(s *ThreadStatus) {
+	if s.poolConn != "" {
+		recordStats(poolConnStats, s.poolConn, s)
+	}
+}
+
+func PoolConnSummaries(time_taken time.Duration) map[string]EndpointSummary {
+	return summarizeStats(poolConnStats, time_taken)
+}
+
+// how latency varies with how many requests shared each connection
+func PrintPoolConns(time_taken time.Duration) {
+	if len(poolConnStats) < 2 {
+		return
+	}
+	poolsMutex.Lock()
+	defer poolsMutex.Unlock()
+	for _, url := range URLS {
+		pool, ok := pools[url]
+		if !ok {
+			continue
+		}
+		for _, c := range pool.conns {
+			if c == nil {
+				continue
+			}
+			e := poolConnStats[c.label]
+			if e == nil {
+				continue
+			}
+			fmt.Printf("Connection %s: Requests: %d, Errors: %d, Rate: %.1f /s, Peak streams: %d, Total: %s\n",
+				c.label, e.Requests, e.Errors, float64(e.Requests)/time_taken.Seconds(), c.peak, e.Latencies.Total.String())
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// transient failures kept with their times; the counts go on past this
+const NUM_STATE_FAILURES = 100
+
+// a connection in TRANSIENT_FAILURE from At until it was READY again
+type StateFailure struct {
+	Conn     string    `json:"conn"`
+	At       time.Time `json:"at"`
+	Duration float64   `json:"duration_ms"` // 0 if it never recovered
+}
+
+type StateSummary struct {
+	Transitions map[string]int `json:"transitions"` // by "FROM->TO"
+	NumFailures int            `json:"num_failures"`
+	Failures    []StateFailure `json:"failures"` // the first NUM_STATE_FAILURES
+}
+
+var (
+	stateMutex   sync.Mutex
+	stateSummary = StateSummary{Transitions: map[string]int{}}
+)
+
+// follows conn's connectivity state until it is closed, counting every
+// transition and with -log-state printing it
+func WatchState(conn *grpc.ClientConn, label string) {
+	// read before returning so the first change isn't missed
+	state := conn.GetState()
+	go func() {
+		failure := -1 // index into Failures while in TRANSIENT_FAILURE
+		var failedAt time.Time
+		for state != connectivity.Shutdown && conn.WaitForStateChange(context.Background(), state) {
+			next := conn.GetState()
+			now := time.Now()
+			if LOG_STATE && !TUI {
+				fmt.Printf("%s %s: %s -> %s\n", now.Format(time.RFC3339Nano), label, state, next)
+			}
+			stateMutex.Lock()
+			if next != connectivity.Shutdown {
+				stateSummary.Transitions[state.String()+"->"+next.String()]++
+			}
+			switch next {
+			case connectivity.TransientFailure:
+				if failedAt.IsZero() {
+					failedAt = now
+					stateSummary.NumFailures++
+					if len(stateSummary.Failures) < NUM_STATE_FAILURES {
+						failure = len(stateSummary.Failures)
+						stateSummary.Failures = append(stateSummary.Failures, StateFailure{Conn: label, At: now})
+					}
+				}
+			case connectivity.Ready:
+				if failure >= 0 {
+					stateSummary.Failures[failure].Duration = ms(now.Sub(failedAt))
+				}
+				failure, failedAt = -1, time.Time{}
+			}
+			stateMutex.Unlock()
+			state = next
+		}
+	}()
+}
+
+func StateTransitions() *StateSummary {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+	s := stateSummary
+	s.Transitions = map[string]int{}
+	for k, v := range stateSummary.Transitions {
+		s.Transitions[k] = v
+	}
+	s.Failures = append([]StateFailure(nil), stateSummary.Failures...)
+	return &s
+}
+
+// transition counts, then when each connection degraded and for how long
+func PrintStateTransitions() {
+	s := StateTransitions()
+	if len(s.Transitions) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(s.Transitions))
+	for k := range s.Transitions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Println("State transitions:")
+	for _, k := range keys {
+		fmt.Printf("\t%s: %d\n", k, s.Transitions[k])
+	}
+	if s.NumFailures == 0 {
+		return
+	}
+	fmt.Println("Transient failures:", s.NumFailures)
+	for _, f := range s.Failures {
+		recovered := "never recovered"
+		if f.Duration != 0 {
+			recovered = fmt.Sprintf("ready after %.0fms", f.Duration)
+		}
+		fmt.Printf("\t%s %s: %s\n", f.At.Format(time.RFC3339Nano), f.Conn, recovered)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// where a fresh connection's setup time goes. the dial is lazy, so all of
+// this happens inside the first rpc's phase rather than in Connect
+type ConnTimes struct {
+	DNS   time.Duration
+	TCP   time.Duration
+	TLS   time.Duration
+	Ready time.Duration // from Connect until the first rpc's headers are sent
+}
+
+// Ready minus the network level steps: http/2 setup and grpc's own work
+func (t *ConnTimes) Grpc() time.Duration {
+	return t.Ready - t.DNS - t.TCP - t.TLS
+}
+
+// set by SetupGrpcOpts; Connect wraps it to time the handshake
+var transportCreds credentials.TransportCredentials
+
+// resolves and connects itself so DNS and TCP can be timed apart. through
+// a proxy there is no DNS of our own, and TCP includes the proxy's setup
+func (c *byteCounter) dial(parent context.Context, addr string) (net.Conn, error) {
+	ctx, cancel := withDialTimeout(parent)
+	defer cancel()
+	conn, err := c.dialTimed(ctx, addr)
+	if err == nil {
+		conn = trackTcp(conn)
+	}
+	return conn, dialTimeout(parent, ctx, "connect", err)
+}
+
+func (c *byteCounter) dialTimed(ctx context.Context, addr string) (net.Conn, error) {
+	if isUnixTarget(addr) {
+		start := time.Now()
+		conn, err := dialUnix(ctx, addr)
+		c.mu.Lock()
+		if c.status != nil {
+			c.status.connTimes.TCP = time.Since(start)
+		}
+		c.mu.Unlock()
+		return conn, err
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	if p, err := proxyFor(addr); err != nil {
+		return nil, err
+	} else if p != nil {
+		conn, err := dialProxy(ctx, p, addr)
+		c.mu.Lock()
+		if c.status != nil {
+			c.status.connTimes.TCP = time.Since(start)
+		}
+		c.mu.Unlock()
+		return conn, err
+	}
+	ips, err := net.DefaultResolver.LookupIP(ctx, ipNetwork("ip"), host)
+	dns := time.Since(start)
+	if err != nil {
+		return nil, err
+	}
+	start = time.Now()
+	var conn net.Conn
+	for _, ip := range ips {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			break
+		}
+	}
+	tcp := time.Since(start)
+	c.mu.Lock()
+	if c.status != nil {
+		c.status.connTimes.DNS, c.status.connTimes.TCP = dns, tcp
+	}
+	c.mu.Unlock()
+	return conn, err
+}
+
+type timedCreds struct {
+	credentials.TransportCredentials
+	counter *byteCounter
+}
+
+func (t *timedCreds) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	start := time.Now()
+	conn, info, err := t.TransportCredentials.ClientHandshake(ctx, authority, conn)
+	t.counter.mu.Lock()
+	if t.counter.status != nil {
+		t.counter.status.connTimes.TLS = time.Since(start)
+	}
+	t.counter.mu.Unlock()
+	return conn, info, err
+}
+
+func (t *timedCreds) Clone() credentials.TransportCredentials {
+	return &timedCreds{t.TransportCredentials.Clone(), t.counter}
+}
+
+// the dialer and credentials that fill in status.connTimes
+func connTimingDialOpts(counter *byteCounter) []grpc.DialOption {
+	opts := []grpc.DialOption{grpc.WithContextDialer(counter.dial)}
+	if transportCreds != nil {
+		opts = append(opts, grpc.WithTransportCredentials(&timedCreds{transportCreds, counter}))
+	}
+	return opts
+}
+
+// every fresh connection's setup; only touched by the goroutine collecting
+// statuses
+var connLatencies = NewLatencyReport()
+
+func RecordConnTimes(s *ThreadStatus) {
+	t := &s.connTimes
+	if t.Ready == 0 {
+		return
+	}
+	connLatencies.add("DNS", t.DNS)
+	connLatencies.add("TCP", t.TCP)
+	connLatencies.add("TLS", t.TLS)
+	connLatencies.add("Grpc", t.Grpc())
+	connLatencies.Total.Add(t.Ready)
+}
+
+func PrintConnTimes() {
+	if connLatencies.Total.Count == 0 {
+		return
+	}
+	fmt.Println("Connection setup:")
+	for _, name := range connLatencies.Order {
+		fmt.Printf("\t%s: %s, share: %.1f%%\n", name, connLatencies.Phases[name].String(), 100*connLatencies.Share(name))
+	}
+	fmt.Printf("\tReady: %s\n", connLatencies.Total.String())
+}
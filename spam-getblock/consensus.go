@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+)
+
+// dumps the whole consensus state at height; this is very heavy on the node,
+// so it is only allowed with -allow-dangerous
+func GetStateToGenesis(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	start := time.Now()
+	doc, err := consensus.NewConsensusClient(conn).StateToGenesis(ctx, int64(height))
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	// size of the document as CBOR, which is how it went over the wire
+	size := len(cbor.Marshal(doc))
+	status.msg = fmt.Sprintf("Height: %d, Size: %d bytes, Rate: %.2f MB/s",
+		height, size, float64(size)/1e6/status.times.Query.Seconds())
+	return status
+}
+
+func GetGenesisDocument(ctx context.Context, _ uint64) ThreadStatus {
+	status := ThreadStatus{times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	start := time.Now()
+	doc, err := consensus.NewConsensusClient(conn).GetGenesisDocument(ctx)
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("ChainID: %s, Height: %d, Size: %d bytes", doc.ChainID, doc.Height, len(cbor.Marshal(doc)))
+	return status
+}
+
+func GetChainContext(ctx context.Context, _ uint64) ThreadStatus {
+	status := ThreadStatus{times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	start := time.Now()
+	chainContext, err := consensus.NewConsensusClient(conn).GetChainContext(ctx)
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("ChainContext: %s", chainContext)
+	return status
+}
+
+func GetSignerNonce(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	address := PickStakingAddress()
+	start := time.Now()
+	nonce, err := consensus.NewConsensusClient(conn).GetSignerNonce(ctx, &consensus.GetSignerNonceRequest{
+		AccountAddress: address,
+		Height:         int64(height),
+	})
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("Height: %d, Address: %s, Nonce: %d", height, address, nonce)
+	return status
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+var (
+	csvFile   *os.File
+	csvWriter *csv.Writer
+)
+
+func csvMs(d time.Duration) string {
+	return strconv.FormatFloat(ms(d), 'f', 3, 64)
+}
+
+// creates -csv and writes the header; rows follow as requests finish
+func OpenCsv() error {
+	f, err := os.Create(CSV)
+	if err != nil {
+		return err
+	}
+	csvFile = f
+	csvWriter = csv.NewWriter(f)
+	header := []string{"round", "call", "connect_ms"}
+	for _, phase := range (&ApiTimes{}).Phases() {
+		header = append(header, phase.Name+"_ms")
+	}
+	header = append(header, "total_ms", "bytes", "code", "error")
+	csvWriter.Write(header)
+	csvWriter.Flush()
+	statusSinks = append(statusSinks, WriteCsvRow)
+	return csvWriter.Error()
+}
+
+// flushed per row so the file can be read while the run is going
+func WriteCsvRow(s *ThreadStatus) {
+	row := []string{strconv.FormatUint(s.ID, 10), CALL, csvMs(s.times.Connect)}
+	for _, phase := range s.times.Phases() {
+		row = append(row, csvMs(phase.Duration))
+	}
+	errStr := ""
+	if s.err != nil {
+		errStr = s.err.Error()
+	}
+	row = append(row, csvMs(s.times.Total()), strconv.FormatInt(s.bytes, 10), errorCode(s.err), errStr)
+	csvWriter.Write(row)
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		fmt.Println("CSV error:", err)
+	}
+}
+
+func CloseCsv() {
+	if csvFile != nil {
+		csvWriter.Flush()
+		csvFile.Close()
+	}
+}
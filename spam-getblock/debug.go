@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	oasisGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// wraps the call's codec to keep the encoded request and the response as
+// received, before decoding
+type captureCodec struct {
+	encoding.Codec
+	req, rsp []byte
+}
+
+func (c *captureCodec) Marshal(v interface{}) ([]byte, error) {
+	b, err := c.Codec.Marshal(v)
+	c.req = b
+	return b, err
+}
+
+func (c *captureCodec) Unmarshal(data []byte, v interface{}) error {
+	c.rsp = append([]byte(nil), data...)
+	return c.Codec.Unmarshal(data, v)
+}
+
+var numDumps uint64
+
+// writes a failed call's encoded request and the response, if one arrived,
+// to -dump-dir; a response that failed to decode is the interesting case
+func dumpCall(method string, codec *captureCodec) error {
+	name := fmt.Sprintf("%d-%s", atomic.AddUint64(&numDumps, 1), strings.ReplaceAll(strings.TrimPrefix(method, "/"), "/", "."))
+	ext := "." + codec.Name()
+	if err := os.WriteFile(filepath.Join(DUMP_DIR, name+".req"+ext), codec.req, 0o644); err != nil {
+		return err
+	}
+	if codec.rsp == nil {
+		return nil
+	}
+	return os.WriteFile(filepath.Join(DUMP_DIR, name+".rsp"+ext), codec.rsp, 0o644)
+}
+
+// -debug: logs every unary call's method, parameters, encoded sizes and
+// outcome to stderr, and with -dump-dir saves failed calls' bodies
+func debugInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	// the last forced codec is the one in effect: cbor from oasisGrpc.Dial,
+	// unless the call picked another, like the health checks
+	var inner encoding.Codec = &oasisGrpc.CBORCodec{}
+	for _, opt := range opts {
+		if o, ok := opt.(grpc.ForceCodecCallOption); ok {
+			inner = o.Codec
+		}
+	}
+	codec := &captureCodec{Codec: inner}
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, append(opts, grpc.ForceCodec(codec))...)
+	took := time.Since(start)
+	line := fmt.Sprintf("debug: %s req=%+v req_bytes=%d rsp_bytes=%d took=%s", method, req, len(codec.req), len(codec.rsp), took)
+	if err != nil {
+		line += " err=" + err.Error()
+		if DUMP_DIR != "" {
+			if derr := dumpCall(method, codec); derr != nil {
+				line += " dump_err=" + derr.Error()
+			}
+		}
+	}
+	fmt.Fprintln(os.Stderr, line)
+	return err
+}
+
+func debugDialOpts() []grpc.DialOption {
+	if !DEBUG {
+		return nil
+	}
+	return []grpc.DialOption{grpc.WithChainUnaryInterceptor(debugInterceptor)}
+}
+
+func CheckDebug() error {
+	if DUMP_DIR == "" {
+		return nil
+	}
+	if !DEBUG {
+		return fmt.Errorf("-dump-dir needs -debug")
+	}
+	return os.MkdirAll(DUMP_DIR, 0o755)
+}
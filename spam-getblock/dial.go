@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// dial-only is about the handshakes, which shared connections would skip
+func CheckDialOnly(ctx context.Context) error {
+	if CONN_MODE != "per-request" {
+		return fmt.Errorf("dial-only needs -conn-mode per-request")
+	}
+	return nil
+}
+
+// dials, waits for the connection to be READY, with -dial-rpc makes one
+// health check on it, and closes it: the load a population of clients
+// that keep reconnecting puts on a gateway
+func DialOnly(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+
+	start := time.Now()
+	conn.Connect()
+	for state := conn.GetState(); state != connectivity.Ready; state = conn.GetState() {
+		if state == connectivity.Shutdown || !conn.WaitForStateChange(ctx, state) {
+			status.times.Ready = time.Since(start)
+			status.err = fmt.Errorf("connection %s, not ready", state)
+			if ctx.Err() != nil {
+				status.err = fmt.Errorf("connection %s, not ready: %w", state, ctx.Err())
+			}
+			conn.Close()
+			return status
+		}
+	}
+	status.times.Ready = time.Since(start)
+	// without an rpc the stats handler never sees the connection ready
+	if status.connTimes.Ready == 0 {
+		status.connTimes.Ready = time.Since(status.start)
+	}
+
+	if DIAL_RPC {
+		start = time.Now()
+		_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: HEALTH_SERVICE}, healthCallOpts...)
+		status.times.Query = time.Since(start)
+		if err != nil {
+			status.err = err
+			conn.Close()
+			return status
+		}
+	}
+
+	start = time.Now()
+	err = conn.Close()
+	status.times.Close = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("Conn: %s, Ready: %s", status.conn, status.times.Ready)
+	return status
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// a connect or TLS handshake that hung past -dial-timeout. it fails the
+// connection, and so the rpc waiting on it, with this in the message, so it
+// is its own error class instead of looking like a slow rpc
+type DialTimeoutError struct {
+	Stage string
+}
+
+func (e *DialTimeoutError) Error() string {
+	return fmt.Sprintf("dial timeout: %s took over %s", e.Stage, DIAL_TIMEOUT)
+}
+
+func (e *DialTimeoutError) Timeout() bool   { return true }
+func (e *DialTimeoutError) Temporary() bool { return true }
+
+func withDialTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if DIAL_TIMEOUT <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, DIAL_TIMEOUT)
+}
+
+// err, or a DialTimeoutError if it came from -dial-timeout rather than from
+// grpc's own connect deadline
+func dialTimeout(parent, ctx context.Context, stage string, err error) error {
+	if err != nil && parent.Err() == nil && ctx.Err() == context.DeadlineExceeded {
+		return &DialTimeoutError{stage}
+	}
+	return err
+}
+
+// bounds the TLS handshake by -dial-timeout
+type handshakeTimeoutCreds struct {
+	credentials.TransportCredentials
+}
+
+func (c *handshakeTimeoutCreds) ClientHandshake(parent context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	ctx, cancel := withDialTimeout(parent)
+	defer cancel()
+	conn, info, err := c.TransportCredentials.ClientHandshake(ctx, authority, conn)
+	return conn, info, dialTimeout(parent, ctx, "TLS handshake", err)
+}
+
+func (c *handshakeTimeoutCreds) Clone() credentials.TransportCredentials {
+	return &handshakeTimeoutCreds{c.TransportCredentials.Clone()}
+}
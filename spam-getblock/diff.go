@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+)
+
+// |t| above which a difference in series rates counts as significant,
+// roughly p < 0.05
+const DIFF_T_CRITICAL = 2.0
+
+func readSummary(path string) (*Summary, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Summary
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// the per bucket rates of a run, without the last partial bucket
+func seriesRates(s *Summary) []float64 {
+	var rates []float64
+	for i, p := range s.Series {
+		if i < len(s.Series)-1 {
+			rates = append(rates, p.Rate)
+		}
+	}
+	return rates
+}
+
+func meanVariance(xs []float64) (float64, float64) {
+	var sum, sq float64
+	for _, x := range xs {
+		sum += x
+	}
+	mean := sum / float64(len(xs))
+	for _, x := range xs {
+		sq += (x - mean) * (x - mean)
+	}
+	return mean, sq / float64(len(xs)-1)
+}
+
+// welch's t statistic of b against a; ok is false with too few samples to
+// say anything
+func welchT(a, b []float64) (t float64, ok bool) {
+	if len(a) < 3 || len(b) < 3 {
+		return 0, false
+	}
+	ma, va := meanVariance(a)
+	mb, vb := meanVariance(b)
+	se := math.Sqrt(va/float64(len(a)) + vb/float64(len(b)))
+	if se == 0 {
+		return 0, false
+	}
+	return (mb - ma) / se, true
+}
+
+// compares two -output json reports and returns the regressions beyond
+// -tolerance. throughput must also differ significantly across the 10s
+// series when both runs have one; percentiles only come as aggregates, so
+// for them the tolerance is the whole test
+func DiffSummaries(base, cur *Summary) []string {
+	var regressions []string
+	report := func(name string, before, after float64, worse bool, note string) {
+		change := 0.0
+		if before != 0 {
+			change = (after - before) / before
+		}
+		mark := ""
+		if worse {
+			mark = "  REGRESSION"
+			regressions = append(regressions, name)
+		}
+		fmt.Printf("%-28s %10.2f -> %10.2f %+7.1f%%%s%s\n", name, before, after, 100*change, note, mark)
+	}
+
+	change := (cur.Rate - base.Rate) / base.Rate
+	note := ""
+	significant := true
+	if t, ok := welchT(seriesRates(base), seriesRates(cur)); ok {
+		note = fmt.Sprintf(" (t=%.1f)", t)
+		significant = math.Abs(t) > DIFF_T_CRITICAL
+	}
+	report("rate /s", base.Rate, cur.Rate, change < -DIFF_TOLERANCE && significant, note)
+
+	baseErrors := float64(base.Errors) / math.Max(float64(base.Requests), 1)
+	curErrors := float64(cur.Errors) / math.Max(float64(cur.Requests), 1)
+	report("error rate %", 100*baseErrors, 100*curErrors, curErrors > baseErrors*(1+DIFF_TOLERANCE), "")
+
+	phases := []string{}
+	for name := range base.Phases {
+		if _, ok := cur.Phases[name]; ok {
+			phases = append(phases, name)
+		}
+	}
+	sort.Strings(phases)
+	compare := func(name string, b, c PercentileSummary) {
+		for _, q := range []struct {
+			name          string
+			before, after float64
+		}{{"p50", b.P50, c.P50}, {"p95", b.P95, c.P95}, {"p99", b.P99, c.P99}} {
+			worse := b.Count != 0 && q.after > q.before*(1+DIFF_TOLERANCE)
+			report(name+" "+q.name+" ms", q.before, q.after, worse, "")
+		}
+	}
+	for _, name := range phases {
+		compare(name, base.Phases[name], cur.Phases[name])
+	}
+	compare("Total", base.Total, cur.Total)
+	return regressions
+}
+
+// grpc-test diff baseline.json current.json; exits 1 on any regression so
+// it can gate a node upgrade
+func RunDiff(basePath, curPath string) error {
+	base, err := readSummary(basePath)
+	if err != nil {
+		return err
+	}
+	cur, err := readSummary(curPath)
+	if err != nil {
+		return err
+	}
+	if base.Requests == 0 || base.Rate == 0 {
+		return fmt.Errorf("%s: empty run", basePath)
+	}
+	regressions := DiffSummaries(base, cur)
+	if len(regressions) != 0 {
+		fmt.Println("Regressions:", len(regressions), "beyond tolerance", DIFF_TOLERANCE)
+		os.Exit(1)
+	}
+	fmt.Println("No regressions beyond tolerance", DIFF_TOLERANCE)
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestWelchT(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		a, b []float64
+		want float64
+		ok   bool
+	}{
+		{"too few", []float64{1, 2}, []float64{1, 2, 3}, 0, false},
+		{"no variance", []float64{5, 5, 5}, []float64{5, 5, 5}, 0, false},
+		{"same", []float64{1, 2, 3}, []float64{1, 2, 3}, 0, true},
+		// means 2 and 5, variances 1, se sqrt(2/3)
+		{"faster", []float64{1, 2, 3}, []float64{4, 5, 6}, 3 / math.Sqrt(2.0/3), true},
+		{"slower", []float64{4, 5, 6}, []float64{1, 2, 3}, -3 / math.Sqrt(2.0/3), true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := welchT(tt.a, tt.b)
+			if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("got %g, %v, expected %g, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDiffSummaries(t *testing.T) {
+	defer func(tolerance float64) { DIFF_TOLERANCE = tolerance }(DIFF_TOLERANCE)
+	DIFF_TOLERANCE = 0.1
+
+	summary := func(rate float64, errors int, p99 float64, series ...float64) *Summary {
+		s := &Summary{
+			Requests: 1000,
+			Errors:   errors,
+			Rate:     rate,
+			Phases:   map[string]PercentileSummary{"GetBlock": {Count: 1000, P50: 10, P95: 20, P99: p99}},
+			Total:    PercentileSummary{Count: 1000, P50: 10, P95: 20, P99: p99},
+		}
+		// the last point is a partial bucket and is left out
+		for _, r := range append(series, 0) {
+			s.Series = append(s.Series, &SeriesPoint{Rate: r})
+		}
+		return s
+	}
+	for _, tt := range []struct {
+		name      string
+		base, cur *Summary
+		want      []string
+	}{
+		{"same", summary(100, 0, 30), summary(100, 0, 30), nil},
+		{"within tolerance", summary(100, 10, 30), summary(95, 10, 32), nil},
+		{"slower", summary(100, 0, 30), summary(80, 0, 30), []string{"rate /s"}},
+		{"slower but noisy",
+			summary(100, 0, 30, 40, 160, 100, 60, 140),
+			summary(80, 0, 30, 150, 20, 90, 130, 10),
+			nil},
+		{"slower and steady",
+			summary(100, 0, 30, 99, 101, 100, 100),
+			summary(80, 0, 30, 79, 81, 80, 80),
+			[]string{"rate /s"}},
+		{"errors", summary(100, 10, 30), summary(100, 20, 30), []string{"error rate %"}},
+		{"p99", summary(100, 0, 30), summary(100, 0, 40), []string{"GetBlock p99 ms", "Total p99 ms"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffSummaries(tt.base, tt.cur); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("regressions %q, expected %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+)
+
+// spreads rank i over [0, span) so the hot rounds of zipf and hotspot are
+// scattered rather than adjacent
+func scatter(i, span uint64) uint64 {
+	return (i * 0x9e3779b97f4a7c15) % span
+}
+
+// zipf generators per range size; rand.Zipf isn't safe for concurrent use
+var (
+	zipfMutex sync.Mutex
+	zipfs     = map[uint64]*rand.Zipf{}
+)
+
+func CheckDistribution() error {
+	switch DISTRIBUTION {
+	case "uniform", "hotspot", "recent", "head":
+	case "zipf":
+		if ZIPF_S <= 1 {
+			return fmt.Errorf("-zipf-s must be > 1")
+		}
+	default:
+		return fmt.Errorf("bad -distribution '%s', expected uniform, zipf, hotspot, recent or head", DISTRIBUTION)
+	}
+	if HOT_FRACTION <= 0 || HOT_FRACTION > 1 || HOT_TRAFFIC < 0 || HOT_TRAFFIC > 1 {
+		return fmt.Errorf("-hot-fraction must be in (0, 1] and -hot-traffic in [0, 1]")
+	}
+	if RECENT_WINDOW == 0 || RECENT_TRAFFIC < 0 || RECENT_TRAFFIC > 1 {
+		return fmt.Errorf("-recent-window must be > 0 and -recent-traffic in [0, 1]")
+	}
+	return nil
+}
+
+// how often SampleRange redraws a known-bad or, with -unique, already drawn
+// round before probing for the next acceptable one
+const MAX_REDRAWS = 1000
+
+// rounds handed out so far, for -unique
+var (
+	drawnMutex     sync.Mutex
+	drawnRounds    = map[uint64]bool{}
+	drawnExhausted bool
+)
+
+func rejectRound(round uint64) bool {
+	return skipRounds[round] || (UNIQUE && drawnRounds[round])
+}
+
+// picks from [min, max) according to -distribution, avoiding known-bad
+// rounds and, with -unique, rounds it has already picked
+func SampleRange(min, max uint64) uint64 {
+	drawnMutex.Lock()
+	defer drawnMutex.Unlock()
+	round := sampleRange(min, max)
+	for i := 0; i < MAX_REDRAWS && rejectRound(round); i++ {
+		round = sampleRange(min, max)
+	}
+	span := max - min
+	for i := uint64(0); i < span && !drawnExhausted && rejectRound(round); i++ {
+		round = min + (round-min+1)%span
+	}
+	if UNIQUE {
+		if drawnRounds[round] && !drawnExhausted {
+			drawnExhausted = true
+			fmt.Printf("Warning: -unique ran out of rounds in %d-%d, repeating\n", min, max)
+		}
+		// only kept with -unique, it grows with every request
+		drawnRounds[round] = true
+	}
+	return round
+}
+
+func sampleRange(min, max uint64) uint64 {
+	span := max - min
+	switch DISTRIBUTION {
+	case "zipf":
+		zipfMutex.Lock()
+		defer zipfMutex.Unlock()
+		z, ok := zipfs[span]
+		if !ok {
+			z = rand.NewZipf(rand.New(rand.NewSource(SEED)), ZIPF_S, 1, span-1)
+			zipfs[span] = z
+		}
+		return min + scatter(z.Uint64(), span)
+	case "hotspot":
+		hot := uint64(float64(span) * HOT_FRACTION)
+		if hot > 0 && rand.Float64() < HOT_TRAFFIC {
+			return min + scatter(rand.Uint64()%hot, span)
+		}
+	case "recent":
+		// like explorers and indexers: mostly the tip, sometimes deep history
+		if span > RECENT_WINDOW {
+			if rand.Float64() < RECENT_TRAFFIC {
+				return max - 1 - (rand.Uint64() % RECENT_WINDOW)
+			}
+			return min + (rand.Uint64() % (span - RECENT_WINDOW))
+		}
+	}
+	return min + (rand.Uint64() % span)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// every -url; URL is the first, which the subcommands and setup use
+var (
+	URLS        = []string{"grpc.oasiscloud.io:443"}
+	URL_WEIGHTS = []int{1}
+)
+
+// -url, repeatable and comma separated, each optionally with =weight; the
+// first one given replaces the default
+type urlList struct {
+	set bool
+}
+
+func (l *urlList) String() string {
+	urls := make([]string, len(URLS))
+	for i, u := range URLS {
+		urls[i] = u
+		if URL_WEIGHTS[i] != 1 {
+			urls[i] += "=" + strconv.Itoa(URL_WEIGHTS[i])
+		}
+	}
+	return strings.Join(urls, ",")
+}
+
+func (l *urlList) Set(s string) error {
+	if !l.set {
+		URLS, URL_WEIGHTS = nil, nil
+		l.set = true
+	}
+	for _, u := range strings.Split(s, ",") {
+		u = strings.TrimSpace(u)
+		if u == "" {
+			continue
+		}
+		weight := 1
+		if i := strings.LastIndex(u, "="); i != -1 {
+			w, err := strconv.Atoi(u[i+1:])
+			if err != nil || w < 1 {
+				return fmt.Errorf("bad weight in -url '%s'", u)
+			}
+			u, weight = u[:i], w
+		}
+		URLS = append(URLS, u)
+		URL_WEIGHTS = append(URL_WEIGHTS, weight)
+	}
+	if len(URLS) == 0 {
+		return fmt.Errorf("empty -url")
+	}
+	URL = URLS[0]
+	return nil
+}
+
+var (
+	endpointMutex   sync.Mutex
+	endpointCurrent []int
+)
+
+// requests go to the endpoints in turn, each getting its share of the
+// weights; smooth weighted round robin, so a 3:1 split goes a a b a rather
+// than a a a b
+func NextEndpoint() string {
+	if len(URLS) == 1 {
+		return URLS[0]
+	}
+	endpointMutex.Lock()
+	defer endpointMutex.Unlock()
+	if endpointCurrent == nil {
+		endpointCurrent = make([]int, len(URLS))
+	}
+	best, total := 0, 0
+	for i, w := range URL_WEIGHTS {
+		endpointCurrent[i] += w
+		total += w
+		if endpointCurrent[i] > endpointCurrent[best] {
+			best = i
+		}
+	}
+	endpointCurrent[best] -= total
+	return URLS[best]
+}
+
+type EndpointStats struct {
+	Requests  int
+	Errors    int
+	Latencies *LatencyReport
+}
+
+// per endpoint when there are several; only touched by the goroutine
+// collecting statuses
+var endpointStats = map[string]*EndpointStats{}
+
+func recordStats(m map[string]*EndpointStats, key string, s *ThreadStatus) {
+	e, ok := m[key]
+	if !ok {
+		e = &EndpointStats{Latencies: NewLatencyReport()}
+		m[key] = e
+	}
+	e.Requests++
+	if s.err != nil {
+		e.Errors++
+	}
+	e.Latencies.Record(&s.times)
+}
+
+func RecordEndpoint(s *ThreadStatus) {
+	if len(URLS) < 2 {
+		return
+	}
+	recordStats(endpointStats, s.endpoint, s)
+}
+
+type EndpointSummary struct {
+	Requests int                          `json:"requests"`
+	Errors   int                          `json:"errors"`
+	Rate     float64                      `json:"rate"`
+	Phases   map[string]PercentileSummary `json:"phases"`
+	Total    PercentileSummary            `json:"total"`
+}
+
+func summarizeStats(m map[string]*EndpointStats, time_taken time.Duration) map[string]EndpointSummary {
+	if len(m) == 0 {
+		return nil
+	}
+	summaries := map[string]EndpointSummary{}
+	for key, e := range m {
+		s := EndpointSummary{
+			Requests: e.Requests,
+			Errors:   e.Errors,
+			Rate:     float64(e.Requests) / time_taken.Seconds(),
+			Phases:   map[string]PercentileSummary{},
+			Total:    e.Latencies.Total.Summary(),
+		}
+		for name, h := range e.Latencies.Phases {
+			s.Phases[name] = h.Summary()
+		}
+		summaries[key] = s
+	}
+	return summaries
+}
+
+func EndpointSummaries(time_taken time.Duration) map[string]EndpointSummary {
+	return summarizeStats(endpointStats, time_taken)
+}
+
+// in -url order, after the combined totals
+func PrintEndpoints(time_taken time.Duration) {
+	for i, url := range URLS {
+		e, ok := endpointStats[url]
+		if !ok {
+			continue
+		}
+		if host, ok := resolvedFrom[url]; ok {
+			url += " (" + host + ")"
+		}
+		fmt.Println("Endpoint:", url, "Weight:", URL_WEIGHTS[i], "Requests:", e.Requests, "Errors:", e.Errors, "Rate:", float32(e.Requests)/float32(time_taken.Seconds()), "/s")
+		for _, name := range e.Latencies.Order {
+			fmt.Printf("  %s: %s\n", name, e.Latencies.Phases[name].String())
+		}
+		fmt.Printf("  Total: %s\n", e.Latencies.Total.String())
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// example rounds kept per error class
+const NUM_ERROR_EXAMPLES = 5
+
+// failed requests with the same grpc code and message, ignoring numbers in
+// the message so "round 123 not found" and "round 456 not found" group
+type ErrorClass struct {
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+	Count    int      `json:"count"`
+	Examples []uint64 `json:"example_rounds"`
+}
+
+var errorNumbers = regexp.MustCompile(`[0-9]+`)
+
+// failed requests by grpc status code; non-grpc errors count as Unknown.
+// only touched by the goroutine collecting statuses
+var (
+	errorKinds   = map[string]int{}
+	errorClasses = map[string]*ErrorClass{}
+)
+
+// the grpc code, except that a failed TLS handshake, which grpc reports as
+// Unavailable like a server that's down, is a setup problem of its own.
+// with TLS 1.3 a rejected client certificate only shows up after the
+// handshake, as an alert while reading the server preface
+func errorCode(err error) string {
+	st := status.Convert(err)
+	if st.Code() == codes.Unavailable && (strings.Contains(st.Message(), "authentication handshake failed") || strings.Contains(st.Message(), "remote error: tls:")) {
+		return "TLSHandshake"
+	}
+	return st.Code().String()
+}
+
+func CountError(s *ThreadStatus) {
+	st := status.Convert(s.err)
+	code := errorCode(s.err)
+	message := errorNumbers.ReplaceAllString(st.Message(), "N")
+	errorKinds[code]++
+	if code == "TLSHandshake" && CERT_FILE == "" && (strings.Contains(message, "certificate required") || strings.Contains(message, "bad certificate") || strings.Contains(message, "handshake failure")) {
+		message += " (the gateway may want a client certificate, see -cert and -key)"
+	}
+	// grpc's own wording doesn't say which knob to turn
+	if st.Code() == codes.ResourceExhausted && strings.Contains(message, "larger than max") {
+		if strings.Contains(message, "send") {
+			message += " (see -max-send-msg-size)"
+		} else {
+			message += " (see -max-recv-msg-size)"
+		}
+	}
+	if strings.Contains(message, "first record does not look like a TLS handshake") {
+		message += " (the endpoint isn't TLS, see -plaintext)"
+	}
+	if st.Code() == codes.Unimplemented && strings.Contains(message, "Decompressor is not installed") {
+		message += " (the server doesn't take -compress " + COMPRESS + ")"
+	}
+	key := code + ": " + message
+	class, ok := errorClasses[key]
+	if !ok {
+		class = &ErrorClass{Code: code, Message: message}
+		errorClasses[key] = class
+	}
+	class.Count++
+	if len(class.Examples) < NUM_ERROR_EXAMPLES {
+		class.Examples = append(class.Examples, s.ID)
+	}
+}
+
+// most frequent first
+func ErrorClasses() []*ErrorClass {
+	classes := make([]*ErrorClass, 0, len(errorClasses))
+	for _, class := range errorClasses {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if classes[i].Count != classes[j].Count {
+			return classes[i].Count > classes[j].Count
+		}
+		return classes[i].Code+classes[i].Message < classes[j].Code+classes[j].Message
+	})
+	return classes
+}
+
+func PrintErrorClasses() {
+	if len(errorKinds) == 0 {
+		return
+	}
+	codes := make([]string, 0, len(errorKinds))
+	for code := range errorKinds {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return errorKinds[codes[i]] > errorKinds[codes[j]] })
+	for _, code := range codes {
+		fmt.Printf("  %s: %d\n", code, errorKinds[code])
+	}
+	for _, class := range ErrorClasses() {
+		fmt.Printf("  %dx %s: %s (e.g. %v)\n", class.Count, class.Code, class.Message, class.Examples)
+	}
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	oasisGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+	governance "github.com/oasisprotocol/oasis-core/go/governance/api"
+	roothash "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	runtime "github.com/oasisprotocol/oasis-core/go/runtime/client/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+	"google.golang.org/grpc"
+)
+
+// consensus backends that have a WatchEvents stream
+var CONSENSUS_EVENT_BACKENDS = []string{"staking", "governance", "roothash"}
+
+type headSeen struct {
+	Height   uint64
+	Received time.Time
+}
+
+type eventSeen struct {
+	Source   string // consensus backend or runtime module
+	Height   uint64
+	Received time.Time
+}
+
+// parses -event-filter; nil means everything
+func EventFilter() map[string]bool {
+	if EVENT_FILTER == "" {
+		return nil
+	}
+	filter := map[string]bool{}
+	for _, s := range strings.Split(EVENT_FILTER, ",") {
+		filter[strings.TrimSpace(s)] = true
+	}
+	return filter
+}
+
+// sdk event keys are the module name followed by a 4 byte code
+func runtimeEventModule(key []byte) string {
+	if len(key) < 4 {
+		return ""
+	}
+	return string(key[:len(key)-4])
+}
+
+// drops of the streams kept by keepWatching, what was backfilled after them
+// and how long until each stream had caught up again
+type streamGaps struct {
+	sync.Mutex
+	Drops    int
+	Missed   int // blocks, or events for event streams, fetched by backfill
+	Errors   int // failed backfills
+	Recovery DurationStats
+}
+
+func (g *streamGaps) String() string {
+	g.Lock()
+	defer g.Unlock()
+	return fmt.Sprintf("Drops: %d, Missed: %d, Backfill errors: %d, Recovery: %s", g.Drops, g.Missed, g.Errors, g.Recovery.String())
+}
+
+type watchedStream[T any] struct {
+	name      string
+	subscribe func(context.Context) (<-chan T, pubsub.ClosableSubscription, error)
+	height    func(T) uint64
+	// fetches what a dropped stream skipped in [from, to), returning how
+	// many blocks or events that was
+	backfill func(ctx context.Context, from, to uint64) (int, error)
+	// for event streams, which don't send every height: the chain height
+	// when the stream dropped, so backfill doesn't start at the last event
+	since func() uint64
+}
+
+// passes everything a stream sends to each until ctx ends. like RunWatch, a
+// dropped stream is resubscribed a second later and the heights it skipped
+// in between backfilled. only the first subscription's error is returned,
+// later ones are printed and retried
+func keepWatching[T any](ctx context.Context, s watchedStream[T], gaps *streamGaps, each func(T)) error {
+	ch, sub, err := s.subscribe(ctx)
+	if err != nil {
+		return err
+	}
+	go func() {
+		var last uint64
+		var droppedAt time.Time
+		for {
+			for v := range ch {
+				height := s.height(v)
+				if !droppedAt.IsZero() {
+					if height <= last {
+						// resent on resubscription
+						continue
+					}
+					if last != 0 && height > last+1 {
+						n, err := s.backfill(ctx, last+1, height)
+						fmt.Printf("Gap: %s %d-%d, Backfilled: %d\n", s.name, last+1, height-1, n)
+						if err != nil {
+							fmt.Println("Backfill error:", err)
+						}
+						gaps.Lock()
+						gaps.Missed += n
+						if err != nil {
+							gaps.Errors++
+						}
+						gaps.Unlock()
+					}
+					fmt.Printf("Recovered %s after: %s\n", s.name, time.Since(droppedAt))
+					gaps.Lock()
+					gaps.Recovery.Add(time.Since(droppedAt))
+					gaps.Unlock()
+					droppedAt = time.Time{}
+				}
+				last = height
+				each(v)
+			}
+			sub.Close()
+			if ctx.Err() != nil {
+				return
+			}
+			gaps.Lock()
+			gaps.Drops++
+			gaps.Unlock()
+			if droppedAt.IsZero() {
+				droppedAt = time.Now()
+				if s.since != nil && s.since() > last {
+					last = s.since()
+				}
+			}
+			fmt.Println("Stream dropped:", s.name, "after height", last)
+			for ch, sub, err = s.subscribe(ctx); err != nil; ch, sub, err = s.subscribe(ctx) {
+				if ctx.Err() != nil {
+					return
+				}
+				fmt.Println("Resubscribe error:", err)
+				time.Sleep(time.Second)
+			}
+		}
+	}()
+	return nil
+}
+
+// fetches the events of the heights [from, to) that a WatchEvents stream
+// skipped, returning how many there were
+func backfillEvents[E any](getEvents func(context.Context, int64) ([]E, error)) func(context.Context, uint64, uint64) (int, error) {
+	return func(ctx context.Context, from, to uint64) (int, error) {
+		n := 0
+		for height := from; height < to; height++ {
+			evs, err := getEvents(ctx, int64(height))
+			if err != nil {
+				return n, fmt.Errorf("backfill height %d: %w", height, err)
+			}
+			n += len(evs)
+		}
+		return n, nil
+	}
+}
+
+// consensus blocks as the head, events from each backend's WatchEvents
+func watchConsensusEvents(ctx context.Context, conn *grpc.ClientConn, filter map[string]bool, gaps *streamGaps, heads chan<- headSeen, events chan<- eventSeen) error {
+	for name := range filter {
+		found := false
+		for _, backend := range CONSENSUS_EVENT_BACKENDS {
+			found = found || name == backend
+		}
+		if !found {
+			return fmt.Errorf("bad -event-filter '%s', consensus backends are %s", name, strings.Join(CONSENSUS_EVENT_BACKENDS, ", "))
+		}
+	}
+
+	client := consensus.NewConsensusClient(conn)
+	var headHeight uint64
+	blocks := watchedStream[*consensus.Block]{
+		name:      "blocks",
+		subscribe: client.WatchBlocks,
+		height:    func(block *consensus.Block) uint64 { return uint64(block.Height) },
+		backfill: func(ctx context.Context, from, to uint64) (int, error) {
+			return BackfillConsensus(ctx, client, int64(from), int64(to))
+		},
+	}
+	err := keepWatching(ctx, blocks, gaps, func(block *consensus.Block) {
+		atomic.StoreUint64(&headHeight, uint64(block.Height))
+		select {
+		case heads <- headSeen{uint64(block.Height), time.Now()}:
+		case <-ctx.Done():
+		}
+	})
+	if err != nil {
+		return err
+	}
+	since := func() uint64 { return atomic.LoadUint64(&headHeight) }
+	send := func(ev eventSeen) {
+		select {
+		case events <- ev:
+		case <-ctx.Done():
+		}
+	}
+
+	if filter == nil || filter["staking"] {
+		client := staking.NewStakingClient(conn)
+		stream := watchedStream[*staking.Event]{
+			name:      "staking",
+			subscribe: client.WatchEvents,
+			height:    func(ev *staking.Event) uint64 { return uint64(ev.Height) },
+			backfill:  backfillEvents(client.GetEvents),
+			since:     since,
+		}
+		err := keepWatching(ctx, stream, gaps, func(ev *staking.Event) {
+			send(eventSeen{"staking", uint64(ev.Height), time.Now()})
+		})
+		if err != nil {
+			return fmt.Errorf("staking: %w", err)
+		}
+	}
+	if filter == nil || filter["governance"] {
+		client := governance.NewGovernanceClient(conn)
+		stream := watchedStream[*governance.Event]{
+			name:      "governance",
+			subscribe: client.WatchEvents,
+			height:    func(ev *governance.Event) uint64 { return uint64(ev.Height) },
+			backfill:  backfillEvents(client.GetEvents),
+			since:     since,
+		}
+		err := keepWatching(ctx, stream, gaps, func(ev *governance.Event) {
+			send(eventSeen{"governance", uint64(ev.Height), time.Now()})
+		})
+		if err != nil {
+			return fmt.Errorf("governance: %w", err)
+		}
+	}
+	if filter == nil || filter["roothash"] {
+		client := roothash.NewRootHashClient(conn)
+		stream := watchedStream[*roothash.Event]{
+			name: "roothash",
+			subscribe: func(ctx context.Context) (<-chan *roothash.Event, pubsub.ClosableSubscription, error) {
+				return client.WatchEvents(ctx, RUNTIME_ID)
+			},
+			height:   func(ev *roothash.Event) uint64 { return uint64(ev.Height) },
+			backfill: backfillEvents(client.GetEvents),
+			since:    since,
+		}
+		err := keepWatching(ctx, stream, gaps, func(ev *roothash.Event) {
+			send(eventSeen{"roothash", uint64(ev.Height), time.Now()})
+		})
+		if err != nil {
+			return fmt.Errorf("roothash: %w", err)
+		}
+	}
+	return nil
+}
+
+// a runtime WatchBlocks stream, backfilled with BackfillRuntime
+func runtimeBlocks(name string, client runtime.RuntimeClient) watchedStream[*roothash.AnnotatedBlock] {
+	return watchedStream[*roothash.AnnotatedBlock]{
+		name: name,
+		subscribe: func(ctx context.Context) (<-chan *roothash.AnnotatedBlock, pubsub.ClosableSubscription, error) {
+			return client.WatchBlocks(ctx, RUNTIME_ID)
+		},
+		height: func(blk *roothash.AnnotatedBlock) uint64 { return blk.Block.Header.Round },
+		backfill: func(ctx context.Context, from, to uint64) (int, error) {
+			return BackfillRuntime(ctx, client, from, to)
+		},
+	}
+}
+
+// runtime blocks as the head; the node has no runtime event stream, so
+// events are fetched with GetEvents as each block arrives, which is what
+// the sdk's WatchEvents does as well
+func watchRuntimeEvents(ctx context.Context, conn *grpc.ClientConn, filter map[string]bool, gaps *streamGaps, heads chan<- headSeen, events chan<- eventSeen) error {
+	client := runtime.NewRuntimeClient(conn)
+	return keepWatching(ctx, runtimeBlocks("blocks", client), gaps, func(blk *roothash.AnnotatedBlock) {
+		round := blk.Block.Header.Round
+		select {
+		case heads <- headSeen{round, time.Now()}:
+		case <-ctx.Done():
+			return
+		}
+		evs, err := client.GetEvents(ctx, &runtime.GetEventsRequest{
+			RuntimeID: RUNTIME_ID,
+			Round:     round,
+		})
+		if err != nil {
+			fmt.Printf("round %d: %s\n", round, err)
+			return
+		}
+		received := time.Now()
+		for _, ev := range evs {
+			module := runtimeEventModule(ev.Key)
+			if filter == nil || filter[module] {
+				select {
+				case events <- eventSeen{module, round, received}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	})
+}
+
+// subscribes to EVENT_LAYER events for DURATION and measures delivery rate
+// and how long after the head block of the same height they arrive
+func RunWatchEvents(ctx context.Context) error {
+	conn, err := oasisGrpc.Dial(URL, dialOpts...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	WatchState(conn, "events")
+
+	ctx, cancel := context.WithTimeout(ctx, DURATION)
+	defer cancel()
+	heads := make(chan headSeen, 16)
+	events := make(chan eventSeen, 1024)
+	filter := EventFilter()
+	var gaps streamGaps
+	switch EVENT_LAYER {
+	case "consensus":
+		err = watchConsensusEvents(ctx, conn, filter, &gaps, heads, events)
+	case "runtime":
+		err = watchRuntimeEvents(ctx, conn, filter, &gaps, heads, events)
+	default:
+		err = fmt.Errorf("unknown -event-layer '%s', expected consensus or runtime", EVENT_LAYER)
+	}
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	headTimes := map[uint64]time.Time{}
+	pending := map[uint64][]time.Time{} // events that arrived before their head block
+	counts := map[string]int{}
+	var lag DurationStats
+	num_events := 0
+	for {
+		select {
+		case <-ctx.Done():
+			elapsed := time.Since(start)
+			sources := make([]string, 0, len(counts))
+			for source := range counts {
+				sources = append(sources, source)
+			}
+			sort.Strings(sources)
+			for _, source := range sources {
+				fmt.Printf("%s: %d\n", source, counts[source])
+			}
+			num_unmatched := 0
+			for _, p := range pending {
+				num_unmatched += len(p)
+			}
+			fmt.Println("Heads:", len(headTimes))
+			fmt.Println("Events:", num_events, "Unmatched:", num_unmatched)
+			fmt.Println("Rate:", float32(num_events)/float32(elapsed.Seconds()), "/s")
+			fmt.Println("Lag:", lag.String())
+			fmt.Println(gaps.String())
+			return nil
+		case head := <-heads:
+			headTimes[head.Height] = head.Received
+			for _, received := range pending[head.Height] {
+				lag.Add(received.Sub(head.Received))
+			}
+			delete(pending, head.Height)
+			fmt.Printf("Height: %d\n", head.Height)
+		case ev := <-events:
+			num_events++
+			counts[ev.Source]++
+			headTime, ok := headTimes[ev.Height]
+			if !ok {
+				pending[ev.Height] = append(pending[ev.Height], ev.Received)
+				continue
+			}
+			lag.Add(ev.Received.Sub(headTime))
+		}
+	}
+}
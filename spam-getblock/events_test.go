@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+)
+
+type closedSub struct{}
+
+func (closedSub) Close() {}
+
+func TestKeepWatching(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		streams     [][]uint64 // what each subscription sends before dropping; the next one stays open
+		since       uint64     // chain height at the drop, 0 for none
+		wantSeen    []uint64
+		wantFetched [][2]uint64
+		wantMissed  int
+	}{
+		{
+			name:     "drop",
+			streams:  [][]uint64{{1, 2, 3}},
+			wantSeen: []uint64{1, 2, 3},
+		},
+		{
+			name:     "resent",
+			streams:  [][]uint64{{1, 2, 3}, {2, 3, 4}},
+			wantSeen: []uint64{1, 2, 3, 4},
+		},
+		{
+			name:        "gap",
+			streams:     [][]uint64{{1, 2}, {5, 6}},
+			wantSeen:    []uint64{1, 2, 5, 6},
+			wantFetched: [][2]uint64{{3, 5}},
+			wantMissed:  2,
+		},
+		{
+			name:        "twice before recovering",
+			streams:     [][]uint64{{1}, {}, {4}},
+			wantSeen:    []uint64{1, 4},
+			wantFetched: [][2]uint64{{2, 4}},
+			wantMissed:  2,
+		},
+		{
+			name:        "sparse events",
+			streams:     [][]uint64{{10}, {31}},
+			since:       20,
+			wantSeen:    []uint64{10, 31},
+			wantFetched: [][2]uint64{{21, 31}},
+			wantMissed:  10,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var mu sync.Mutex
+			var seen []uint64
+			var fetched [][2]uint64
+			done := make(chan struct{})
+			subscriptions := 0
+			stream := watchedStream[uint64]{
+				name: tt.name,
+				subscribe: func(ctx context.Context) (<-chan uint64, pubsub.ClosableSubscription, error) {
+					ch := make(chan uint64, 8)
+					if subscriptions == len(tt.streams) {
+						// the last one stays open until the test is done
+						close(done)
+						go func() {
+							<-ctx.Done()
+							close(ch)
+						}()
+						return ch, closedSub{}, nil
+					}
+					for _, h := range tt.streams[subscriptions] {
+						ch <- h
+					}
+					close(ch)
+					subscriptions++
+					return ch, closedSub{}, nil
+				},
+				height: func(h uint64) uint64 { return h },
+				backfill: func(ctx context.Context, from, to uint64) (int, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					fetched = append(fetched, [2]uint64{from, to})
+					return int(to - from), nil
+				},
+			}
+			if tt.since != 0 {
+				stream.since = func() uint64 { return tt.since }
+			}
+			var gaps streamGaps
+			err := keepWatching(ctx, stream, &gaps, func(h uint64) {
+				mu.Lock()
+				defer mu.Unlock()
+				seen = append(seen, h)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			<-done
+			cancel()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(seen, tt.wantSeen) {
+				t.Errorf("seen %v, expected %v", seen, tt.wantSeen)
+			}
+			if !reflect.DeepEqual(fetched, tt.wantFetched) {
+				t.Errorf("backfilled %v, expected %v", fetched, tt.wantFetched)
+			}
+			gaps.Lock()
+			defer gaps.Unlock()
+			if gaps.Drops != len(tt.streams) || gaps.Missed != tt.wantMissed {
+				t.Errorf("drops %d, missed %d, expected %d and %d", gaps.Drops, gaps.Missed, len(tt.streams), tt.wantMissed)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oasisprotocol/oasis-sdk/client-sdk/go/client"
+	"github.com/oasisprotocol/oasis-sdk/client-sdk/go/modules/core"
+	"github.com/oasisprotocol/oasis-sdk/client-sdk/go/modules/evm"
+	"github.com/oasisprotocol/oasis-sdk/client-sdk/go/types"
+)
+
+// gas limit for evm-simulate
+const EVM_GAS_LIMIT = 10_000_000
+
+// set once by ParseEvmCall
+var (
+	evmTo     []byte
+	evmData   []byte
+	evmCaller [20]byte
+)
+
+func decodeHexFlag(name, value string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("bad -%s: %w", name, err)
+	}
+	return b, nil
+}
+
+func ParseEvmCall(ctx context.Context) (err error) {
+	if evmTo, err = decodeHexFlag("evm-to", EVM_TO); err != nil {
+		return err
+	}
+	if len(evmTo) != 20 {
+		return fmt.Errorf("-evm-to must be a 20 byte address")
+	}
+	if evmData, err = decodeHexFlag("evm-data", EVM_DATA); err != nil {
+		return err
+	}
+	caller, err := decodeHexFlag("evm-caller", EVM_CALLER)
+	if err != nil {
+		return err
+	}
+	if len(caller) != 20 {
+		return fmt.Errorf("-evm-caller must be a 20 byte address")
+	}
+	copy(evmCaller[:], caller)
+	return nil
+}
+
+// evm.SimulateCall goes through the confidential query path on sapphire
+func SimulateEvmCall(ctx context.Context, round uint64) ThreadStatus {
+	status := ThreadStatus{ID: round, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	rc := client.New(conn, RUNTIME_ID)
+	gasPrice := make([]byte, 32)
+	value := make([]byte, 32)
+	start := time.Now()
+	result, err := evm.NewV1(rc).SimulateCall(ctx, round, gasPrice, EVM_GAS_LIMIT, evmCaller[:], evmTo, value, evmData)
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("Round: %d, Result: 0x%s", round, hex.EncodeToString(result))
+	return status
+}
+
+func EstimateEvmGas(ctx context.Context, round uint64) ThreadStatus {
+	status := ThreadStatus{ID: round, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	rc := client.New(conn, RUNTIME_ID)
+	tx := evm.NewV1(rc).Call(evmTo, make([]byte, 32), evmData).GetTransaction()
+	caller := types.CallerAddress{EthAddress: &evmCaller}
+	start := time.Now()
+	gas, err := core.NewV1(rc).EstimateGasForCaller(ctx, round, caller, tx, false)
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("Round: %d, Gas: %d", round, gas)
+	return status
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	oasisGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	roothash "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	runtime "github.com/oasisprotocol/oasis-core/go/runtime/client/api"
+)
+
+type roundSeen struct {
+	Round    uint64
+	Received time.Time
+	Polled   bool
+}
+
+// follows the runtime head with WatchBlocks and with GetBlock(latest)
+// polling side by side on separate connections for DURATION, and reports
+// how much later polling learns about each round than streaming
+func RunFollow(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, DURATION)
+	defer cancel()
+	seen := make(chan roundSeen, 64)
+
+	streamConn, err := oasisGrpc.Dial(URL, dialOpts...)
+	if err != nil {
+		return err
+	}
+	defer streamConn.Close()
+	WatchState(streamConn, "stream")
+	var gaps streamGaps
+	stream := runtimeBlocks("stream", runtime.NewRuntimeClient(streamConn))
+	err = keepWatching(ctx, stream, &gaps, func(blk *roothash.AnnotatedBlock) {
+		select {
+		case seen <- roundSeen{blk.Block.Header.Round, time.Now(), false}:
+		case <-ctx.Done():
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	pollConn, err := oasisGrpc.Dial(URL, dialOpts...)
+	if err != nil {
+		return err
+	}
+	defer pollConn.Close()
+	WatchState(pollConn, "poll")
+	var num_polls, num_poll_errors int64
+	go func() {
+		client := runtime.NewRuntimeClient(pollConn)
+		var lastRound uint64
+		for ctx.Err() == nil {
+			blk, err := client.GetBlock(ctx, &runtime.GetBlockRequest{
+				RuntimeID: RUNTIME_ID,
+				Round:     runtime.RoundLatest,
+			})
+			atomic.AddInt64(&num_polls, 1)
+			if err != nil {
+				atomic.AddInt64(&num_poll_errors, 1)
+			} else if blk.Header.Round != lastRound {
+				lastRound = blk.Header.Round
+				select {
+				case seen <- roundSeen{lastRound, time.Now(), true}:
+				case <-ctx.Done():
+				}
+			}
+			time.Sleep(POLL_INTERVAL)
+		}
+	}()
+
+	streamed := map[uint64]time.Time{}
+	polled := map[uint64]time.Time{}
+	var diff DurationStats
+	num_polled_first := 0
+	for {
+		select {
+		case <-ctx.Done():
+			num_skipped := 0
+			for round := range streamed {
+				if _, ok := polled[round]; !ok {
+					num_skipped++
+				}
+			}
+			fmt.Println("Streamed rounds:", len(streamed), "Polled rounds:", len(polled), "Skipped by polling:", num_skipped)
+			fmt.Println("Polls:", atomic.LoadInt64(&num_polls), "Errors:", atomic.LoadInt64(&num_poll_errors))
+			fmt.Println("Stream", gaps.String())
+			fmt.Println("Polling behind streaming:", diff.String())
+			fmt.Println("Polling first:", num_polled_first)
+			return nil
+		case s := <-seen:
+			if s.Polled {
+				polled[s.Round] = s.Received
+			} else {
+				streamed[s.Round] = s.Received
+			}
+			streamTime, ok1 := streamed[s.Round]
+			pollTime, ok2 := polled[s.Round]
+			if !ok1 || !ok2 {
+				continue
+			}
+			d := pollTime.Sub(streamTime)
+			diff.Add(d)
+			if d < 0 {
+				num_polled_first++
+			}
+			fmt.Printf("Round: %d, PollingBehind: %s\n", s.Round, d)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	governance "github.com/oasisprotocol/oasis-core/go/governance/api"
+)
+
+func GetProposals(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	start := time.Now()
+	proposals, err := governance.NewGovernanceClient(conn).Proposals(ctx, int64(height))
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("Height: %d, NumProposals: %d", height, len(proposals))
+	return status
+}
+
+// fetches the votes of a random proposal that exists at height; only the
+// Votes call itself is timed
+func GetVotes(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	client := governance.NewGovernanceClient(conn)
+	proposals, err := client.Proposals(ctx, int64(height))
+	if err != nil {
+		status.err = err
+		return status
+	}
+	if len(proposals) == 0 {
+		status.msg = fmt.Sprintf("Height: %d, no proposals", height)
+		return status
+	}
+	proposal := proposals[rand.Intn(len(proposals))]
+
+	start := time.Now()
+	votes, err := client.Votes(ctx, &governance.ProposalQuery{Height: int64(height), ProposalID: proposal.ID})
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("Height: %d, ProposalID: %d, NumVotes: %d", height, proposal.ID, len(votes))
+	return status
+}
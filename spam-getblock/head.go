@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	oasisGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+	roothash "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	runtime "github.com/oasisprotocol/oasis-core/go/runtime/client/api"
+)
+
+// latest runtime round and consensus height, kept current by StartHeadWatcher
+var (
+	headRound  uint64
+	headHeight uint64
+)
+
+// for -distribution head
+func HeadRound() uint64 {
+	return atomic.LoadUint64(&headRound)
+}
+
+func HeadHeight() uint64 {
+	return atomic.LoadUint64(&headHeight)
+}
+
+// fetches the latest round and height, then follows both with WatchBlocks
+// until ctx is done, resubscribing when a stream drops
+func StartHeadWatcher(ctx context.Context) error {
+	conn, err := oasisGrpc.Dial(URL, dialOpts...)
+	if err != nil {
+		return err
+	}
+	rc := runtime.NewRuntimeClient(conn)
+	cc := consensus.NewConsensusClient(conn)
+
+	blk, err := rc.GetBlock(ctx, &runtime.GetBlockRequest{RuntimeID: RUNTIME_ID, Round: roothash.RoundLatest})
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	atomic.StoreUint64(&headRound, blk.Header.Round)
+	block, err := cc.GetBlock(ctx, consensus.HeightLatest)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	atomic.StoreUint64(&headHeight, uint64(block.Height))
+
+	go func() {
+		defer conn.Close()
+		<-ctx.Done()
+	}()
+	go func() {
+		for ctx.Err() == nil {
+			blocks, sub, err := rc.WatchBlocks(ctx, RUNTIME_ID)
+			if err != nil {
+				fmt.Println("Head watcher error:", err)
+				time.Sleep(time.Second)
+				continue
+			}
+			for blk := range blocks {
+				atomic.StoreUint64(&headRound, blk.Block.Header.Round)
+			}
+			sub.Close()
+		}
+	}()
+	go func() {
+		for ctx.Err() == nil {
+			blocks, sub, err := cc.WatchBlocks(ctx)
+			if err != nil {
+				fmt.Println("Head watcher error:", err)
+				time.Sleep(time.Second)
+				continue
+			}
+			for block := range blocks {
+				atomic.StoreUint64(&headHeight, uint64(block.Height))
+			}
+			sub.Close()
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// -header key=value, repeatable; every call carries these as metadata, for
+// gateways that route or rate limit on them and to tag test traffic
+type headerList struct {
+	pairs []string // key, value, key, value, ...
+}
+
+var HEADERS headerList
+
+func (h *headerList) String() string {
+	var s []string
+	for i := 0; i < len(h.pairs); i += 2 {
+		s = append(s, h.pairs[i]+"="+h.pairs[i+1])
+	}
+	return strings.Join(s, ",")
+}
+
+func (h *headerList) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	key = strings.ToLower(strings.TrimSpace(key))
+	if !ok || key == "" {
+		return fmt.Errorf("bad -header '%s', expected key=value", s)
+	}
+	if strings.HasPrefix(key, "grpc-") || strings.HasPrefix(key, ":") {
+		return fmt.Errorf("bad -header '%s', %s is reserved", s, key)
+	}
+	h.pairs = append(h.pairs, key, value)
+	return nil
+}
+
+func headerDialOpts() []grpc.DialOption {
+	if len(HEADERS.pairs) == 0 {
+		return nil
+	}
+	add := func(ctx context.Context) context.Context {
+		return metadata.AppendToOutgoingContext(ctx, HEADERS.pairs...)
+	}
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(metadataInterceptor(add)),
+		grpc.WithChainStreamInterceptor(streamMetadataInterceptor(add)),
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHeaderListSet(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		args []string
+		want []string
+		ok   bool
+	}{
+		{"one", []string{"x-test=1"}, []string{"x-test", "1"}, true},
+		{"repeated", []string{"a=1", "b=2"}, []string{"a", "1", "b", "2"}, true},
+		{"lower cased key", []string{" X-Api-Key =Secret"}, []string{"x-api-key", "Secret"}, true},
+		{"empty value", []string{"x-empty="}, []string{"x-empty", ""}, true},
+		{"equals in value", []string{"x-q=a=b"}, []string{"x-q", "a=b"}, true},
+		{"no value", []string{"x-test"}, nil, false},
+		{"no key", []string{"=1"}, nil, false},
+		{"grpc reserved", []string{"grpc-timeout=1S"}, nil, false},
+		{"pseudo header", []string{":authority=x"}, nil, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var h headerList
+			var err error
+			for _, arg := range tt.args {
+				if err = h.Set(arg); err != nil {
+					break
+				}
+			}
+			if (err == nil) != tt.ok {
+				t.Fatalf("error %v", err)
+			}
+			if !reflect.DeepEqual(h.pairs, tt.want) {
+				t.Fatalf("pairs %q, expected %q", h.pairs, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/proto"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// oasisGrpc.Dial forces cbor on every call; the standard health service
+// speaks protobuf
+var healthCallOpts = []grpc.CallOption{grpc.ForceCodec(encoding.GetCodec(proto.Name))}
+
+// grpc.health.v1 Health/Check, as answered by the node or the gateway in
+// front of it
+func CheckHealth(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	start := time.Now()
+	rsp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: HEALTH_SERVICE}, healthCallOpts...)
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("Service: '%s', Status: %s", HEALTH_SERVICE, rsp.Status)
+	if rsp.Status != healthpb.HealthCheckResponse_SERVING {
+		status.err = fmt.Errorf("not serving: %s", rsp.Status)
+	}
+	return status
+}
+
+// grpc.health.v1 Health/Watch; times until the first status arrives, which
+// the server has to send immediately
+func WatchHealth(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	start := time.Now()
+	stream, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{Service: HEALTH_SERVICE}, healthCallOpts...)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	rsp, err := stream.Recv()
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("Service: '%s', Status: %s", HEALTH_SERVICE, rsp.Status)
+	if rsp.Status != healthpb.HealthCheckResponse_SERVING {
+		status.err = fmt.Errorf("not serving: %s", rsp.Status)
+	}
+	return status
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type hedgeResult struct {
+	status ThreadStatus
+	hedge  bool
+}
+
+// wraps a call so that if it hasn't answered within -hedge-after the same
+// call is sent again, which Connect puts on the next endpoint or pool
+// connection, or with per-request connections on a fresh one. the first
+// success wins and the other is cancelled; if both fail the first error is
+// kept. the loser's status is dropped, so its bytes and timings don't count
+func Hedged(call_f func(context.Context, uint64) ThreadStatus) func(context.Context, uint64) ThreadStatus {
+	return func(ctx context.Context, height uint64) ThreadStatus {
+		start := time.Now()
+		results := make(chan hedgeResult, 2)
+		run := func(hedge bool) context.CancelFunc {
+			// its own slot, so shared connections find the right status
+			subctx, cancel := context.WithCancel(WithStatusSlot(ctx))
+			go func() { results <- hedgeResult{call_f(subctx, height), hedge} }()
+			return cancel
+		}
+		cancelPrimary := run(false)
+		defer cancelPrimary()
+
+		timer := time.NewTimer(HEDGE_AFTER)
+		defer timer.Stop()
+		var first *hedgeResult
+		pending, hedged := 1, false
+		for pending > 0 {
+			select {
+			case <-timer.C:
+				cancelHedge := run(true)
+				defer cancelHedge()
+				pending++
+				hedged = true
+			case r := <-results:
+				pending--
+				if r.status.err == nil || first == nil {
+					r := r
+					first = &r
+				}
+				if r.status.err == nil {
+					pending = 0
+				} else if !hedged {
+					// failed before the hedge was due; no point sending it
+					timer.Stop()
+				}
+			}
+		}
+		status := first.status
+		status.hedged = hedged
+		status.hedgeWon = first.hedge
+		// the call's latency is from the first send, not from the hedge's
+		if first.hedge {
+			status.times.Hedge = first.status.start.Sub(start)
+		}
+		return status
+	}
+}
+
+type Hedging struct {
+	After     float64 `json:"after_ms"`
+	Requests  int     `json:"requests"`
+	Hedged    int     `json:"hedged"`    // a second request was sent
+	HedgeWon  int     `json:"hedge_won"` // and answered first
+	HedgedOk  int     `json:"hedged_ok"` // either of the two succeeded
+	hedgedLat Histogram
+}
+
+// only touched by the goroutine collecting statuses
+var hedging Hedging
+
+func RecordHedge(s *ThreadStatus) {
+	if HEDGE_AFTER <= 0 {
+		return
+	}
+	hedging.Requests++
+	if !s.hedged {
+		return
+	}
+	hedging.Hedged++
+	if s.hedgeWon {
+		hedging.HedgeWon++
+	}
+	if s.err == nil {
+		hedging.HedgedOk++
+	}
+	hedging.hedgedLat.Add(s.times.Total())
+}
+
+func HedgeSummary() *Hedging {
+	if HEDGE_AFTER <= 0 {
+		return nil
+	}
+	hedging.After = ms(HEDGE_AFTER)
+	return &hedging
+}
+
+// how much extra load hedging cost and how often it paid off
+func PrintHedging() {
+	h := &hedging
+	if HEDGE_AFTER <= 0 || h.Requests == 0 {
+		return
+	}
+	fmt.Printf("Hedged after %s: %d / %d (%.1f%%), hedge won: %d", HEDGE_AFTER, h.Hedged, h.Requests, 100*float64(h.Hedged)/float64(h.Requests), h.HedgeWon)
+	if h.Hedged != 0 {
+		fmt.Printf(" (%.1f%% of hedged), Total when hedged: %s", 100*float64(h.HedgeWon)/float64(h.Hedged), h.hedgedLat.String())
+	}
+	fmt.Println()
+}
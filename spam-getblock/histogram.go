@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"time"
+)
+
+// log-linear buckets in the style of HdrHistogram: exact below
+// 2^HISTOGRAM_SUB_BITS microseconds, then 2^(HISTOGRAM_SUB_BITS-1) buckets
+// per power of two, i.e. under 2% relative error at any magnitude
+const HISTOGRAM_SUB_BITS = 7
+
+type Histogram struct {
+	Count  uint64
+	Min    time.Duration
+	Max    time.Duration
+	Sum    time.Duration
+	counts []uint64
+}
+
+func histogramIndex(us uint64) int {
+	if us < 1<<HISTOGRAM_SUB_BITS {
+		return int(us)
+	}
+	shift := bits.Len64(us) - HISTOGRAM_SUB_BITS
+	half := uint64(1) << (HISTOGRAM_SUB_BITS - 1)
+	return int(2*half + uint64(shift-1)*half + (us>>shift - half))
+}
+
+// midpoint of the bucket, in microseconds
+func histogramValue(i int) uint64 {
+	if i < 1<<HISTOGRAM_SUB_BITS {
+		return uint64(i)
+	}
+	half := 1 << (HISTOGRAM_SUB_BITS - 1)
+	shift := (i-2*half)/half + 1
+	sub := uint64((i-2*half)%half + half)
+	return sub<<shift + (uint64(1)<<shift)/2
+}
+
+func (h *Histogram) Add(d time.Duration) {
+	if h.Count == 0 || d < h.Min {
+		h.Min = d
+	}
+	if h.Count == 0 || d > h.Max {
+		h.Max = d
+	}
+	h.Count++
+	h.Sum += d
+	us := uint64(0)
+	if d > 0 {
+		us = uint64(d / time.Microsecond)
+	}
+	i := histogramIndex(us)
+	for len(h.counts) <= i {
+		h.counts = append(h.counts, 0)
+	}
+	h.counts[i]++
+}
+
+// value at quantile q in [0, 1], clamped to the exact min and max
+func (h *Histogram) Percentile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	target := uint64(math.Ceil(q * float64(h.Count)))
+	if target == 0 {
+		return h.Min
+	}
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= target {
+			d := time.Duration(histogramValue(i)) * time.Microsecond
+			if d < h.Min {
+				return h.Min
+			}
+			if d > h.Max {
+				return h.Max
+			}
+			return d
+		}
+	}
+	return h.Max
+}
+
+func (h *Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+func (h *Histogram) String() string {
+	return fmt.Sprintf("n: %d, min: %s, mean: %s, p50: %s, p95: %s, p99: %s, p99.9: %s, max: %s",
+		h.Count, h.Min, h.Mean(), h.Percentile(0.5), h.Percentile(0.95), h.Percentile(0.99), h.Percentile(0.999), h.Max)
+}
+
+// per-phase and overall latency histograms of a run
+type LatencyReport struct {
+	Phases map[string]*Histogram
+	Order  []string // phases in the order first seen
+	Total  Histogram
+}
+
+func NewLatencyReport() *LatencyReport {
+	return &LatencyReport{Phases: map[string]*Histogram{}}
+}
+
+func (r *LatencyReport) add(name string, d time.Duration) {
+	h, ok := r.Phases[name]
+	if !ok {
+		h = &Histogram{}
+		r.Phases[name] = h
+		r.Order = append(r.Order, name)
+	}
+	h.Add(d)
+}
+
+// phases that were not run are left out, as in ApiTimes.String
+func (r *LatencyReport) Record(t *ApiTimes) {
+	r.add("Connect", t.Connect)
+	for _, phase := range t.Phases() {
+		if phase.Duration != 0 {
+			r.add(phase.Name, phase.Duration)
+		}
+	}
+	r.Total.Add(t.Total())
+}
+
+// the phase's part of all the time spent, summed over every request
+func (r *LatencyReport) Share(name string) float64 {
+	if r.Total.Sum == 0 {
+		return 0
+	}
+	return float64(r.Phases[name].Sum) / float64(r.Total.Sum)
+}
+
+// one line per phase across all requests, so the dominating phase stands out
+func (r *LatencyReport) Print() {
+	for _, name := range r.Order {
+		fmt.Printf("%s: %s, share: %.1f%%\n", name, r.Phases[name].String(), 100*r.Share(name))
+	}
+	fmt.Printf("Total: %s\n", r.Total.String())
+}
+
+// every request of the run; only touched by the goroutine collecting statuses
+var latencies = NewLatencyReport()
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		us   uint64
+	}{
+		{"zero", 0},
+		{"exact", 1},
+		{"last exact", 1<<HISTOGRAM_SUB_BITS - 1},
+		{"first log", 1 << HISTOGRAM_SUB_BITS},
+		{"odd", 1234},
+		{"second", 1_000_000},
+		{"minute", 60_000_000},
+		{"power of two", 1 << 40},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			i := histogramIndex(tt.us)
+			v := histogramValue(i)
+			if tt.us < 1<<HISTOGRAM_SUB_BITS {
+				if v != tt.us {
+					t.Fatalf("value %d in bucket %d, expected exact", v, i)
+				}
+				return
+			}
+			if err := math.Abs(float64(v)-float64(tt.us)) / float64(tt.us); err > 0.02 {
+				t.Fatalf("value %d in bucket %d is %.1f%% off", v, i, 100*err)
+			}
+			if histogramIndex(v) != i {
+				t.Fatalf("midpoint %d lands in bucket %d, not %d", v, histogramIndex(v), i)
+			}
+		})
+	}
+}
+
+func TestHistogramIndexMonotonic(t *testing.T) {
+	last := 0
+	for us := uint64(0); us < 1<<20; us++ {
+		i := histogramIndex(us)
+		if i < last || i > last+1 {
+			t.Fatalf("%dus in bucket %d after bucket %d", us, i, last)
+		}
+		last = i
+	}
+}
+
+func TestHistogramPercentile(t *testing.T) {
+	var h Histogram
+	for i := 1; i <= 1000; i++ {
+		h.Add(time.Duration(i) * time.Millisecond)
+	}
+	for _, tt := range []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0, time.Millisecond},
+		{0.5, 500 * time.Millisecond},
+		{0.99, 990 * time.Millisecond},
+		{1, 1000 * time.Millisecond},
+	} {
+		got := h.Percentile(tt.q)
+		if math.Abs(float64(got-tt.want)) > 0.02*float64(tt.want) {
+			t.Errorf("p%g: %s, expected about %s", 100*tt.q, got, tt.want)
+		}
+	}
+	if h.Mean() != 500500*time.Microsecond {
+		t.Errorf("mean: %s", h.Mean())
+	}
+
+	var empty Histogram
+	if empty.Percentile(0.5) != 0 || empty.Mean() != 0 {
+		t.Errorf("empty histogram: p50 %s, mean %s", empty.Percentile(0.5), empty.Mean())
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// bars in the latency distribution chart
+const HTML_LATENCY_BINS = 40
+
+const htmlReportTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Call}} {{.RunID}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { border: 1px solid #ccc; padding: 0.2em 0.6em; text-align: right; }
+th { background: #eee; }
+td.l, th.l { text-align: left; }
+svg { display: block; margin-bottom: 1.5em; font-size: 11px; }
+</style>
+</head>
+<body>
+<h1>{{.Call}} against {{.URL}}</h1>
+<p>Run {{.RunID}}, seed {{.S.Seed}}, api {{.S.ApiVersion}}, {{.S.ConnMode}} connections</p>
+{{if .S.InsecureSkipVerify}}<p><strong>Warning:</strong> server certificates were not verified (-insecure-skip-verify), this is a lab run, not a production measurement</p>
+{{end}}<table>
+<tr><th class="l">Requests</th><td>{{.S.Requests}}</td></tr>
+<tr><th class="l">Errors</th><td>{{.S.Errors}}</td></tr>
+<tr><th class="l">Total time</th><td>{{printf "%.1f" .S.TotalSeconds}} s</td></tr>
+<tr><th class="l">Rate</th><td>{{printf "%.1f" .S.Rate}} /s</td></tr>
+</table>
+
+{{if .S.Assertions}}<h2>Assertions {{if .S.Passed}}passed{{else}}<span style="color: #b00">FAILED</span>{{end}}</h2>
+<table>
+<tr><th class="l">Assertion</th><th>Limit</th><th>Actual</th><th class="l"></th></tr>
+{{range .S.Assertions}}<tr><td class="l">{{.Name}}</td><td>{{.Limit}}</td><td>{{.Actual}}</td><td class="l">{{if .Passed}}ok{{else}}<b style="color: #b00">FAIL</b>{{end}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Latency distribution</h2>
+{{.Distribution}}
+
+<h2>Phases</h2>
+<table>
+<tr><th class="l">Phase</th><th>n</th><th>p50 ms</th><th>p90 ms</th><th>p99 ms</th><th>p99.9 ms</th><th>max ms</th></tr>
+{{range .Phases}}<tr><td class="l">{{.Name}}</td><td>{{.P.Count}}</td><td>{{printf "%.1f" .P.P50}}</td><td>{{printf "%.1f" .P.P90}}</td><td>{{printf "%.1f" .P.P99}}</td><td>{{printf "%.1f" .P.P999}}</td><td>{{printf "%.1f" .P.Max}}</td></tr>
+{{end}}</table>
+{{.PhaseChart}}
+
+<h2>Over time</h2>
+{{.Throughput}}
+{{.Latency}}
+
+<h2>Errors</h2>
+{{if .S.ErrorClasses}}<table>
+<tr><th>Count</th><th class="l">Code</th><th class="l">Message</th><th class="l">Example rounds</th></tr>
+{{range .S.ErrorClasses}}<tr><td>{{.Count}}</td><td class="l">{{.Code}}</td><td class="l">{{.Message}}</td><td class="l">{{.Examples}}</td></tr>
+{{end}}</table>{{else}}<p>None</p>{{end}}
+
+<details><summary>Config</summary>
+<table>
+{{range .Config}}<tr><td class="l">{{.Name}}</td><td class="l">{{.Value}}</td></tr>
+{{end}}</table>
+</details>
+</body>
+</html>
+`
+
+type htmlPhase struct {
+	Name string
+	P    PercentileSummary
+}
+
+type htmlFlag struct {
+	Name, Value string
+}
+
+// an svg bar chart; labels under every few bars
+func svgBars(title string, values []float64, labels []string, color string) template.HTML {
+	const width, height, bottom = 800.0, 200.0, 30.0
+	max := 0.0
+	for _, v := range values {
+		max = math.Max(max, v)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg width="%.0f" height="%.0f"><text x="0" y="12">%s</text>`, width, height+bottom, template.HTMLEscapeString(title))
+	if len(values) == 0 || max == 0 {
+		b.WriteString("</svg>")
+		return template.HTML(b.String())
+	}
+	w := width / float64(len(values))
+	step := len(values)/10 + 1
+	for i, v := range values {
+		h := (height - 20) * v / max
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %g</title></rect>`,
+			float64(i)*w, height-h, math.Max(w-1, 1), h, color, template.HTMLEscapeString(labels[i]), v)
+		if i%step == 0 {
+			fmt.Fprintf(&b, `<text x="%.1f" y="%.0f">%s</text>`, float64(i)*w, height+15, template.HTMLEscapeString(labels[i]))
+		}
+	}
+	fmt.Fprintf(&b, `<text x="%.0f" y="24" text-anchor="end">max %g</text></svg>`, width, max)
+	return template.HTML(b.String())
+}
+
+// the run's total latencies regrouped into log-spaced bins from min to max
+func latencyDistribution(h *Histogram) ([]float64, []string) {
+	if h.Count == 0 {
+		return nil, nil
+	}
+	lo, hi := math.Max(float64(h.Min.Microseconds()), 1), math.Max(float64(h.Max.Microseconds()), 2)
+	if hi <= lo {
+		hi = lo * 2
+	}
+	bins := make([]float64, HTML_LATENCY_BINS)
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		v := math.Max(float64(histogramValue(i)), lo)
+		bin := int(math.Log(v/lo) / math.Log(hi/lo) * HTML_LATENCY_BINS)
+		if bin >= HTML_LATENCY_BINS {
+			bin = HTML_LATENCY_BINS - 1
+		}
+		bins[bin] += float64(c)
+	}
+	labels := make([]string, HTML_LATENCY_BINS)
+	for i := range labels {
+		us := lo * math.Pow(hi/lo, float64(i)/HTML_LATENCY_BINS)
+		labels[i] = fmt.Sprintf("%.3gms", us/1000)
+	}
+	return bins, labels
+}
+
+// renders latency distribution, per phase percentiles, throughput and
+// latency over time and the error table into one html file with no
+// external resources, for attaching to tickets
+func WriteHtmlReport(s *Summary) error {
+	data := struct {
+		S                        *Summary
+		Call, URL, RunID         string
+		Phases                   []htmlPhase
+		Config                   []htmlFlag
+		Distribution, PhaseChart template.HTML
+		Throughput, Latency      template.HTML
+	}{S: s, Call: CALL, URL: URL, RunID: RUN_ID}
+
+	values, labels := latencyDistribution(&latencies.Total)
+	data.Distribution = svgBars("requests by total latency", values, labels, "#4a7ab5")
+
+	var p99s []float64
+	var names []string
+	for _, name := range latencies.Order {
+		p := s.Phases[name]
+		data.Phases = append(data.Phases, htmlPhase{name, p})
+		p99s = append(p99s, p.P99)
+		names = append(names, name)
+	}
+	data.Phases = append(data.Phases, htmlPhase{"Total", s.Total})
+	data.PhaseChart = svgBars("p99 ms by phase", p99s, names, "#b5744a")
+
+	var rates, p99 []float64
+	var offsets []string
+	for _, p := range s.Series {
+		rates = append(rates, p.Rate)
+		p99 = append(p99, p.P99)
+		offsets = append(offsets, fmt.Sprintf("%.0fs", p.Offset))
+	}
+	data.Throughput = svgBars("requests/s", rates, offsets, "#4ab56a")
+	data.Latency = svgBars("p99 ms", p99, offsets, "#b54a4a")
+
+	for name, value := range s.Config {
+		data.Config = append(data.Config, htmlFlag{name, value})
+	}
+	sort.Slice(data.Config, func(i, j int) bool { return data.Config[i].Name < data.Config[j].Name })
+
+	f, err := os.Create(REPORT)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return template.Must(template.New("report").Parse(htmlReportTemplate)).Execute(f, data)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the interval being aggregated for -influx, shared between the collecting
+// goroutine and the flusher
+var (
+	influxMutex   sync.Mutex
+	influxReport  = NewLatencyReport()
+	influxErrors  int
+	influxStop    = make(chan struct{})
+	influxStopped = make(chan struct{})
+)
+
+var influxEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+
+// a dead -influx url mustn't hold up the flusher, and CloseInflux with it
+var influxClient = &http.Client{Timeout: 10 * time.Second}
+
+func OpenInflux() {
+	statusSinks = append(statusSinks, func(s *ThreadStatus) {
+		influxMutex.Lock()
+		defer influxMutex.Unlock()
+		influxReport.Record(&s.times)
+		if s.err != nil {
+			influxErrors++
+		}
+	})
+	go func() {
+		defer close(influxStopped)
+		ticker := time.NewTicker(INFLUX_INTERVAL)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				FlushInflux()
+			case <-influxStop:
+				FlushInflux()
+				return
+			}
+		}
+	}()
+}
+
+// flushes the last partial interval
+func CloseInflux() {
+	close(influxStop)
+	<-influxStopped
+}
+
+func influxHistogramLine(tags string, h *Histogram, ts int64) string {
+	return fmt.Sprintf("grpctest_latency,%s count=%di,p50_ms=%f,p90_ms=%f,p99_ms=%f,p99_9_ms=%f,max_ms=%f %d",
+		tags, h.Count, ms(h.Percentile(0.5)), ms(h.Percentile(0.9)), ms(h.Percentile(0.99)), ms(h.Percentile(0.999)), ms(h.Max), ts)
+}
+
+// writes the interval's aggregates as line protocol, to -influx as a file
+// (appended) or as an http write endpoint
+func FlushInflux() {
+	influxMutex.Lock()
+	report, num_errors := influxReport, influxErrors
+	influxReport, influxErrors = NewLatencyReport(), 0
+	influxMutex.Unlock()
+	if report.Total.Count == 0 {
+		return
+	}
+
+	ts := time.Now().UnixNano()
+	tags := "call=" + influxEscaper.Replace(CALL) + ",endpoint=" + influxEscaper.Replace(URL)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "grpctest_requests,%s count=%di,errors=%di %d\n", tags, report.Total.Count, num_errors, ts)
+	for _, name := range report.Order {
+		buf.WriteString(influxHistogramLine(tags+",phase="+influxEscaper.Replace(name), report.Phases[name], ts) + "\n")
+	}
+	buf.WriteString(influxHistogramLine(tags+",phase=total", &report.Total, ts) + "\n")
+
+	var err error
+	if strings.HasPrefix(INFLUX, "http://") || strings.HasPrefix(INFLUX, "https://") {
+		var rsp *http.Response
+		rsp, err = influxClient.Post(INFLUX, "text/plain; charset=utf-8", &buf)
+		if err == nil {
+			rsp.Body.Close()
+			if rsp.StatusCode/100 != 2 {
+				err = fmt.Errorf("http %s", rsp.Status)
+			}
+		}
+	} else {
+		var f *os.File
+		f, err = os.OpenFile(INFLUX, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err == nil {
+			_, err = f.Write(buf.Bytes())
+			f.Close()
+		}
+	}
+	if err != nil {
+		fmt.Println("Influx error:", err)
+	}
+}
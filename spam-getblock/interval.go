@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the interval being summarized for -interval, shared between the
+// collecting goroutine and the printer
+var (
+	intervalMutex   sync.Mutex
+	intervalReport  = NewLatencyReport()
+	intervalErrors  int
+	intervalStop    = make(chan struct{})
+	intervalStopped = make(chan struct{})
+)
+
+func StartIntervalSummaries() {
+	statusSinks = append(statusSinks, func(s *ThreadStatus) {
+		intervalMutex.Lock()
+		defer intervalMutex.Unlock()
+		intervalReport.Record(&s.times)
+		if s.err != nil {
+			intervalErrors++
+		}
+	})
+	go func() {
+		defer close(intervalStopped)
+		ticker := time.NewTicker(INTERVAL)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				PrintInterval()
+			case <-intervalStop:
+				PrintInterval()
+				return
+			}
+		}
+	}()
+}
+
+// prints the last partial interval
+func StopIntervalSummaries() {
+	close(intervalStop)
+	<-intervalStopped
+}
+
+// one line per interval: requests, errors and p99 of every phase seen
+func PrintInterval() {
+	intervalMutex.Lock()
+	report, num_errors := intervalReport, intervalErrors
+	intervalReport, intervalErrors = NewLatencyReport(), 0
+	intervalMutex.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Interval %s: Requests: %d, Errors: %d", time.Now().Format("15:04:05"), report.Total.Count, num_errors)
+	if report.Total.Count != 0 {
+		b.WriteString(", p99")
+		for _, name := range report.Order {
+			fmt.Fprintf(&b, " %s=%.1fms", name, ms(report.Phases[name].Percentile(0.99)))
+		}
+		fmt.Fprintf(&b, " Total=%.1fms", ms(report.Total.Percentile(0.99)))
+	}
+	fmt.Println(b.String())
+}
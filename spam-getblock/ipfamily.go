@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"time"
+)
+
+func CheckIpFamily() error {
+	switch IP_FAMILY {
+	case "auto", "4", "6":
+		return nil
+	}
+	return fmt.Errorf("bad -ip-family '%s', expected 4, 6 or auto", IP_FAMILY)
+}
+
+// -ip-family as a network for net.Dialer, and for LookupIP with prefix "ip"
+func ipNetwork(prefix string) string {
+	if IP_FAMILY == "auto" {
+		return prefix
+	}
+	return prefix + IP_FAMILY
+}
+
+// IPv4 or IPv6 by the peer address an rpc went to, "" for unix sockets
+func ipFamily(peer string) string {
+	host, _, err := net.SplitHostPort(peer)
+	if err != nil {
+		return ""
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return "IPv4"
+	}
+	return "IPv6"
+}
+
+// by address family of the connection; only touched by the goroutine
+// collecting statuses
+var familyStats = map[string]*EndpointStats{}
+
+func RecordIpFamily(s *ThreadStatus) {
+	if family := ipFamily(s.peer); family != "" {
+		recordStats(familyStats, family, s)
+	}
+}
+
+func IpFamilySummaries(time_taken time.Duration) map[string]EndpointSummary {
+	return summarizeStats(familyStats, time_taken)
+}
+
+// so a slow v6 path stands out next to v4 when both are in use
+func PrintIpFamilies(time_taken time.Duration) {
+	families := make([]string, 0, len(familyStats))
+	for family := range familyStats {
+		families = append(families, family)
+	}
+	sort.Strings(families)
+	for _, family := range families {
+		e := familyStats[family]
+		fmt.Printf("%s: Requests: %d, Errors: %d, Rate: %.1f /s, Total: %s\n",
+			family, e.Requests, e.Errors, float64(e.Requests)/time_taken.Seconds(), e.Latencies.Total.String())
+	}
+}
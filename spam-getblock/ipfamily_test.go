@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestIpFamily(t *testing.T) {
+	for _, tt := range []struct {
+		peer string
+		want string
+	}{
+		{"127.0.0.1:443", "IPv4"},
+		{"[::1]:443", "IPv6"},
+		{"[2001:db8::1]:9000", "IPv6"},
+		{"[::ffff:10.0.0.1]:443", "IPv4"},
+		{"localhost:443", ""},
+		{"/run/oasis/internal.sock", ""},
+		{"", ""},
+	} {
+		t.Run(tt.peer, func(t *testing.T) {
+			if got := ipFamily(tt.peer); got != tt.want {
+				t.Fatalf("got %q, expected %q", got, tt.want)
+			}
+		})
+	}
+}
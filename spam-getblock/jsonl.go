@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// one finished request, as a line of -out-jsonl
+type StatusLine struct {
+	Time     time.Time          `json:"time"` // when it finished
+	ID       uint64             `json:"id"`
+	Call     string             `json:"call"`
+	Times    map[string]float64 `json:"times_ms"`
+	Total    float64            `json:"total_ms"`
+	Bytes    int64              `json:"bytes"`
+	Code     string             `json:"code"`
+	Error    string             `json:"error,omitempty"`
+	Msg      string             `json:"msg,omitempty"`
+	Metadata map[string]string  `json:"metadata,omitempty"`
+}
+
+var jsonlFile *os.File
+
+// creates -out-jsonl; lines are written unbuffered so it can be tailed
+func OpenJsonl() error {
+	f, err := os.Create(OUT_JSONL)
+	if err != nil {
+		return err
+	}
+	jsonlFile = f
+	statusSinks = append(statusSinks, WriteJsonl)
+	return nil
+}
+
+func WriteJsonl(s *ThreadStatus) {
+	line := StatusLine{
+		Time:     time.Now(),
+		ID:       s.ID,
+		Call:     CALL,
+		Times:    map[string]float64{"Connect": ms(s.times.Connect)},
+		Total:    ms(s.times.Total()),
+		Bytes:    s.bytes,
+		Code:     errorCode(s.err),
+		Msg:      s.msg,
+		Metadata: s.metadata,
+	}
+	for _, phase := range s.times.Phases() {
+		if phase.Duration != 0 {
+			line.Times[phase.Name] = ms(phase.Duration)
+		}
+	}
+	if s.err != nil {
+		line.Error = s.err.Error()
+	}
+	b, err := json.Marshal(line)
+	if err == nil {
+		_, err = jsonlFile.Write(append(b, '\n'))
+	}
+	if err != nil {
+		fmt.Println("JSONL error:", err)
+	}
+}
+
+func CloseJsonl() {
+	if jsonlFile != nil {
+		jsonlFile.Close()
+	}
+}
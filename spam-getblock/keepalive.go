@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/stats"
+)
+
+// -keepalive-* as a dial option; nil to leave grpc's defaults
+func keepaliveDialOpts() []grpc.DialOption {
+	if KEEPALIVE_TIME <= 0 {
+		return nil
+	}
+	return []grpc.DialOption{grpc.WithKeepaliveParams(keepalive.ClientParameters{
+		Time:                KEEPALIVE_TIME,
+		Timeout:             KEEPALIVE_TIMEOUT,
+		PermitWithoutStream: KEEPALIVE_PERMIT_WITHOUT_STREAM,
+	})}
+}
+
+// failed requests whose connection went away under them, by how
+type Disconnects struct {
+	GoAway      int   `json:"goaway"`       // the server sent GOAWAY, e.g. too_many_pings
+	PingTimeout int   `json:"ping_timeout"` // no ack to a keepalive ping
+	Closed      int   `json:"closed"`       // any other transport close or reset
+	SharedEnded int64 `json:"shared_ended"` // shared connections that ended during the run
+}
+
+// only touched by the goroutine collecting statuses, except SharedEnded
+var disconnects Disconnects
+
+// set by ClosePools so its own closes don't count
+var poolsClosing int32
+
+func RecordDisconnect(s *ThreadStatus) {
+	if s.err == nil {
+		return
+	}
+	msg := strings.ToLower(s.err.Error())
+	switch {
+	case strings.Contains(msg, "goaway") || strings.Contains(msg, "too_many_pings") || strings.Contains(msg, "enhance_your_calm"):
+		disconnects.GoAway++
+	case strings.Contains(msg, "keepalive ping"):
+		disconnects.PingTimeout++
+	case strings.Contains(msg, "transport is closing") || strings.Contains(msg, "connection reset") || strings.Contains(msg, "connection closed"):
+		disconnects.Closed++
+	}
+}
+
+// counts a shared connection's transport ending, after which grpc
+// reconnects it
+func (c *byteCounter) HandleConn(_ context.Context, s stats.ConnStats) {
+	if _, ok := s.(*stats.ConnEnd); ok && c.shared && atomic.LoadInt32(&poolsClosing) == 0 {
+		atomic.AddInt64(&disconnects.SharedEnded, 1)
+	}
+}
+
+func PrintDisconnects() {
+	d := disconnects
+	d.SharedEnded = atomic.LoadInt64(&disconnects.SharedEnded)
+	if d == (Disconnects{}) && KEEPALIVE_TIME <= 0 {
+		return
+	}
+	fmt.Println("Disconnects: GOAWAY:", d.GoAway, "Keepalive ping timeouts:", d.PingTimeout, "Closed:", d.Closed, "Shared connections lost:", d.SharedEnded)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	oasisGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+	keymanager "github.com/oasisprotocol/oasis-core/go/keymanager/api"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+// set once by LoadKeyManagerID
+var keyManagerID common.Namespace
+
+// looks up the key manager of the runtime in the registry
+func LoadKeyManagerID(ctx context.Context) error {
+	conn, err := oasisGrpc.Dial(URL, dialOpts...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	rt, err := registry.NewRegistryClient(conn).GetRuntime(ctx, &registry.GetRuntimeQuery{
+		Height: consensus.HeightLatest,
+		ID:     RUNTIME_ID,
+	})
+	if err != nil {
+		return err
+	}
+	if rt.KeyManager == nil {
+		return fmt.Errorf("runtime %s has no key manager", rt.ID)
+	}
+	keyManagerID = *rt.KeyManager
+	return nil
+}
+
+func GetKeyManagerStatus(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	start := time.Now()
+	km, err := keymanager.NewKeymanagerClient(conn).GetStatus(ctx, &registry.NamespaceQuery{
+		Height: int64(height),
+		ID:     keyManagerID,
+	})
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("Height: %d, KeyManager: %s, Initialized: %t, Secure: %t, NumNodes: %d",
+		height, km.ID, km.IsInitialized, km.IsSecure, len(km.Nodes))
+	return status
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+func CheckLbPolicy() error {
+	switch LB_POLICY {
+	case "":
+		return nil
+	case "pick_first", "round_robin":
+		if RESOLVE_ALL {
+			return fmt.Errorf("-lb-policy balances over the addresses of each -url itself, it can't be used with -resolve-all")
+		}
+		return nil
+	}
+	return fmt.Errorf("bad -lb-policy '%s', expected pick_first or round_robin", LB_POLICY)
+}
+
+// with -lb-policy a plain host:port goes through grpc's dns resolver, so
+// the balancer sees every address of the name instead of just the first
+func lbTarget(endpoint string) string {
+	if LB_POLICY == "" || strings.Contains(endpoint, "://") || isUnixTarget(endpoint) {
+		return endpoint
+	}
+	return "dns:///" + endpoint
+}
+
+// per backend address the balancer sent requests to, with -lb-policy; only
+// touched by the goroutine collecting statuses
+var backendStats = map[string]*EndpointStats{}
+
+func RecordBackend(s *ThreadStatus) {
+	if LB_POLICY != "" && s.peer != "" {
+		recordStats(backendStats, s.peer, s)
+	}
+}
+
+func BackendSummaries(time_taken time.Duration) map[string]EndpointSummary {
+	return summarizeStats(backendStats, time_taken)
+}
+
+// each backend's part of the requests, which round_robin should keep even
+// and pick_first should put on one
+func PrintBackends(time_taken time.Duration) {
+	if LB_POLICY == "" || len(backendStats) == 0 {
+		return
+	}
+	backends := make([]string, 0, len(backendStats))
+	num_requests := 0
+	for backend, e := range backendStats {
+		backends = append(backends, backend)
+		num_requests += e.Requests
+	}
+	sort.Strings(backends)
+	fmt.Println("Backends with", LB_POLICY+":", len(backends))
+	for _, backend := range backends {
+		e := backendStats[backend]
+		fmt.Printf("\t%s: Requests: %d (%.1f%%), Errors: %d, Rate: %.1f /s, Total: %s\n",
+			backend, e.Requests, 100*float64(e.Requests)/float64(num_requests), e.Errors, float64(e.Requests)/time_taken.Seconds(), e.Latencies.Total.String())
+	}
+}
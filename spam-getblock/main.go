@@ -3,12 +3,14 @@ package main
 import (
 	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"flag"
 	"fmt"
 	"io"
+	"math"
 	"math/rand"
-	"sync"
+	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
@@ -19,6 +21,7 @@ import (
 	"github.com/oasisprotocol/oasis-sdk/client-sdk/go/types"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 
 	nexusRuntime "github.com/oasisprotocol/nexus/analyzer/runtime"
 	"github.com/oasisprotocol/nexus/log"
@@ -31,40 +34,719 @@ var (
 	NUM_REQUESTS int
 	DELAY time.Duration
 	TIMEOUT time.Duration
+	CALL string
+	ADDRESS string
+	ADDRESSES_FILE string
+	ADDRESS_SAMPLING string
+	HARVEST_ROUNDS int
+	ALLOW_DANGEROUS bool
+	STORAGE_KEY string
+	STORAGE_PREFIX string
+	TX_FILE string
+	TX_LAYER string
+	EVM_TO string
+	EVM_DATA string
+	EVM_CALLER string
+	NODE_STATUS bool
+	API_VERSION string
+	DURATION time.Duration
+	SUBSCRIBERS int
+	EVENT_LAYER string
+	EVENT_FILTER string
+	POLL_INTERVAL time.Duration
+	METHOD string
+	PAYLOAD string
+	HEALTH_SERVICE string
+	DIAL_RPC bool
+	LOG_STATE bool
+	PROXY string
+	PLAINTEXT bool
+	IP_FAMILY string
+	DIAL_TIMEOUT time.Duration
+	RETRY_MAX_ATTEMPTS int
+	HEDGE_AFTER time.Duration
+	DEBUG bool
+	DUMP_DIR string
+	TCP_INFO bool
+	CERT_FILE string
+	KEY_FILE string
+	INSECURE_SKIP_VERIFY bool
+	SNI string
+	TLS_MIN_VERSION string
+	TLS_MAX_VERSION string
+	TLS_CIPHERS string
+	PIN_CERT string
+	RETRY_CODES string
+	RETRY_INITIAL_BACKOFF time.Duration
+	RETRY_MAX_BACKOFF time.Duration
+	RETRY_BACKOFF_MULTIPLIER float64
+	WEB3_URL string
+	NEXUS_URL string
+	RANGE_FROM uint64
+	RANGE_TO uint64
+	WINDOW int
+	ROUNDS_FILE string
+	SKIP_ROUNDS_FILE string
+	UNIQUE bool
+	OUTPUT string
+	REPORT string
+	SQLITE string
+	POSTGRES string
+	APDEX_THRESHOLD time.Duration
+	SLOWEST int
+	CAPTURE_METADATA string
+	CONN_MODE string
+	POOL_SIZE int
+	POOL_ASSIGN string
+	RESOLVE_ALL bool
+	KEEPALIVE_TIME time.Duration
+	KEEPALIVE_TIMEOUT time.Duration
+	KEEPALIVE_PERMIT_WITHOUT_STREAM bool
+	MAX_RECV_MSG_SIZE int
+	MAX_SEND_MSG_SIZE int
+	LB_POLICY string
+	FIND_STREAM_LIMIT bool
+	STREAM_LIMIT_MAX int
+	STREAM_LIMIT_STEP time.Duration
+	WAIT_FOR_READY bool
+	BACKOFF_BASE_DELAY time.Duration
+	BACKOFF_MULTIPLIER float64
+	BACKOFF_JITTER float64
+	BACKOFF_MAX_DELAY time.Duration
+	MIN_CONNECT_TIMEOUT time.Duration
+	COMPRESS string
+	COMPRESS_COMPARE bool
+	DIFF_TOLERANCE float64
+	OUT string
+	CSV string
+	OUT_JSONL string
+	METRICS_ADDR string
+	PUSHGATEWAY string
+	JOB string
+	RUN_ID string
+	STATSD string
+	STATSD_PREFIX string
+	STATSD_FORMAT string
+	INFLUX string
+	INFLUX_INTERVAL time.Duration
+	OTLP_ENDPOINT string
+	OTLP_TRACES bool
+	OTLP_METRICS bool
+	OTLP_METRICS_INTERVAL time.Duration
+	TUI bool
+	PROGRESS bool
+	INTERVAL time.Duration
+	SEED int64
+	RUNTIME string
+	RUNTIME_ID common.Namespace
+	RUNTIME_NAME string
+	ALL_RUNTIMES bool
+	DISTRIBUTION string
+	ZIPF_S float64
+	HOT_FRACTION float64
+	HOT_TRAFFIC float64
+	RECENT_WINDOW uint64
+	RECENT_TRAFFIC float64
 
 	dialOpts []grpc.DialOption
 )
 
 func SetupGrpcOpts() {
-	certPool, err := x509.SystemCertPool()
-	if err != nil || certPool == nil {
-		certPool = x509.NewCertPool()
-	}
+	// CheckCaCerts already read them
+	certPool, _ := rootCAs()
 	creds := credentials.NewTLS(&tls.Config{
-		RootCAs: certPool,
+		RootCAs:               certPool,
+		Certificates:          clientCerts,
+		InsecureSkipVerify:    INSECURE_SKIP_VERIFY,
+		ServerName:            SNI, // empty leaves it to the dial target's host
+		MinVersion:            tlsMinVersion,
+		MaxVersion:            tlsMaxVersion,
+		CipherSuites:          tlsCipherSuites,
+		VerifyPeerCertificate: pinVerifier(),
+		// shared, so later connections resume and the TLS summary counts it
+		ClientSessionCache: tls.NewLRUClientSessionCache(0),
 	})
+	if INSECURE_SKIP_VERIFY && !PLAINTEXT {
+		fmt.Fprintln(os.Stderr, "WARNING: -insecure-skip-verify: server certificates are not verified, anyone in the path could be answering; this is a lab run, not a production measurement")
+	}
+	if PLAINTEXT || isUnixTarget(URL) {
+		creds = insecure.NewCredentials()
+	} else {
+		if DIAL_TIMEOUT > 0 {
+			creds = &handshakeTimeoutCreds{creds}
+		}
+		creds = &tlsParamsCreds{creds}
+	}
+	transportCreds = creds
 	dialOpts = []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	dialOpts = append(dialOpts, keepaliveDialOpts()...)
+	dialOpts = append(dialOpts, netDialOpts()...)
+	dialOpts = append(dialOpts, headerDialOpts()...)
+	dialOpts = append(dialOpts, debugDialOpts()...)
+	dialOpts = append(dialOpts, readinessDialOpts()...)
+	dialOpts = append(dialOpts, serviceConfigDialOpts()...)
+	dialOpts = append(dialOpts, retryDialOpts()...)
+	// after oasis-core's own defaults in oasisGrpc.Dial, so these win
+	if MAX_RECV_MSG_SIZE > 0 {
+		dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(MAX_RECV_MSG_SIZE)))
+	}
+	if MAX_SEND_MSG_SIZE > 0 {
+		dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(MAX_SEND_MSG_SIZE)))
+	}
 }
 
 func main() {
-	flag.StringVar(&URL, "url", "grpc.oasiscloud.io:443", "grpc endpoint")
+	URL = URLS[0]
+	flag.Var(&urlList{}, "url", "grpc endpoint, or a node's unix:/path/internal.sock; repeat or comma separate for several, which requests go to in turn, in proportion to an optional =weight, e.g. a:443=3,b:443")
 	flag.IntVar(&NUM_REQUESTS, "n", 1, "number of requests")
+	flag.BoolVar(&ALL_RUNTIMES, "all-runtimes", false, "split -n between every active compute runtime in the registry instead of using -runtime")
+	flag.StringVar(&RUNTIME, "runtime", "sapphire", "runtime to query: hex namespace or one of "+RuntimeNames())
 	flag.DurationVar(&DELAY, "delay", 0*time.Millisecond, "delay between requests")
 	flag.DurationVar(&TIMEOUT, "timeout", 60*time.Second, "timeout for each request")
+	flag.DurationVar(&DIAL_TIMEOUT, "dial-timeout", 0, "fail a connection whose TCP connect or TLS handshake takes longer than this, with its own error, instead of letting it use up -timeout; 0 for none")
+	flag.StringVar(&CALL, "call", "getblock", "call to benchmark: "+CallNames())
+	flag.StringVar(&ADDRESS, "address", "", "comma separated account addresses for account queries")
+	flag.StringVar(&ADDRESSES_FILE, "addresses-file", "", "file of addresses for account queries, one per line; oasis1 or, for runtime accounts, 0x eth addresses")
+	flag.StringVar(&ADDRESS_SAMPLING, "address-sampling", "random", "how account queries pick from the address list: random, round-robin or zipf (uses -zipf-s)")
+	flag.IntVar(&HARVEST_ROUNDS, "harvest-rounds", 20, "number of random rounds to collect tx hashes from for tx-by-hash")
+	flag.BoolVar(&ALLOW_DANGEROUS, "allow-dangerous", false, "allow calls that put heavy load on the node or change chain state (state-to-genesis, submit)")
+	flag.StringVar(&STORAGE_KEY, "storage-key", "6163636f756e7473", "hex key for storage-sync SyncGet, e.g. hex of \"accounts\"")
+	flag.StringVar(&STORAGE_PREFIX, "storage-prefix", "6163636f756e7473", "hex prefix for storage-sync SyncGetPrefixes, e.g. hex of \"accounts\"")
+	flag.StringVar(&TX_FILE, "tx-file", "", "file of hex encoded pre-signed transactions, one per line, for submit (testnets/local nets only)")
+	flag.StringVar(&TX_LAYER, "tx-layer", "consensus", "layer the -tx-file transactions are for: consensus or runtime")
+	flag.StringVar(&EVM_TO, "evm-to", "", "hex contract address for evm-simulate and estimate-gas")
+	flag.StringVar(&EVM_DATA, "evm-data", "", "hex call data for evm-simulate and estimate-gas")
+	flag.StringVar(&EVM_CALLER, "evm-caller", "0000000000000000000000000000000000000000", "hex caller address for evm-simulate and estimate-gas")
+	flag.BoolVar(&NODE_STATUS, "node-status", false, "collect node version and sync status (from the control API if available) for the report")
+	flag.StringVar(&API_VERSION, "api-version", "auto", "node API generation: auto, damask or cobalt (for old archive nodes)")
+	flag.DurationVar(&DURATION, "duration", 1*time.Minute, "how long to run the watch, watch-events, follow and soak subcommands")
+	flag.IntVar(&SUBSCRIBERS, "subscribers", 10, "number of concurrent runtime block subscriptions for soak")
+	flag.StringVar(&EVENT_LAYER, "event-layer", "consensus", "event stream for watch-events: consensus or runtime")
+	flag.StringVar(&EVENT_FILTER, "event-filter", "", "comma separated consensus backends (staking, governance, roothash) or runtime modules for watch-events; empty for all")
+	flag.DurationVar(&POLL_INTERVAL, "poll-interval", 0, "sleep between GetBlock(latest) polls for follow; 0 polls as fast as the node answers")
+	flag.StringVar(&METHOD, "method", "", "full grpc method for raw, e.g. oasis-core.RuntimeClient/GetBlock")
+	flag.StringVar(&PAYLOAD, "payload", "", "file holding the cbor request body for raw; empty sends null")
+	flag.BoolVar(&DIAL_RPC, "dial-rpc", false, "make one health check on each dial-only connection before closing it")
+	flag.StringVar(&HEALTH_SERVICE, "health-service", "", "service name for the health and health-watch calls; empty for the server as a whole")
+	flag.StringVar(&WEB3_URL, "web3-url", "", "sapphire web3 json-rpc gateway for web3-compare")
+	flag.StringVar(&NEXUS_URL, "nexus-url", "", "nexus http api base url for nexus-compare")
+	flag.Uint64Var(&RANGE_FROM, "from", 0, "first round/height of a sequential range fetch instead of -n random ones")
+	flag.Uint64Var(&RANGE_TO, "to", 0, "last round/height (inclusive) of a sequential range fetch; 0 samples randomly")
+	flag.IntVar(&WINDOW, "window", 10, "requests in flight during a -from/-to range fetch")
+	flag.Uint64Var(&MIN_ROUND, "min-round", 0, "lowest runtime round to sample")
+	flag.Uint64Var(&MAX_ROUND, "max-round", 0, "runtime round to sample below; 0 for the latest")
+	flag.Uint64Var(&MIN_HEIGHT, "min-height", 0, "lowest consensus height to sample")
+	flag.Uint64Var(&MAX_HEIGHT, "max-height", 0, "consensus height to sample below; 0 for the latest")
+	flag.StringVar(&SKIP_ROUNDS_FILE, "skip-rounds-file", "", "file of known-bad rounds/heights never to sample, one per line; their errors are reported separately")
+	flag.BoolVar(&UNIQUE, "unique", false, "request each random round/height at most once per run, like a backfill, so server-side caches don't flatter throughput")
+	flag.StringVar(&OUTPUT, "output", "text", "summary format: text, json for dashboards, or markdown for issues")
+	flag.Float64Var(&DIFF_TOLERANCE, "tolerance", 0.1, "relative change that counts as a regression in diff, e.g. 0.1 for 10%")
+	flag.DurationVar(&ASSERT_P50, "assert-p50", 0, "exit nonzero if the total p50 latency is above this")
+	flag.DurationVar(&ASSERT_P99, "assert-p99", 0, "exit nonzero if the total p99 latency is above this, e.g. 500ms")
+	flag.Var(&ASSERT_ERROR_RATE, "assert-error-rate", "exit nonzero if more than this fraction of requests fail, e.g. 1% or 0.01")
+	flag.Float64Var(&ASSERT_MIN_RATE, "assert-min-rate", 0, "exit nonzero if fewer than this many requests per second completed")
+	flag.StringVar(&CONN_MODE, "conn-mode", "per-request", "per-request dials a connection for every request (handshake included in the timings), shared sends every request over one connection per endpoint, pool spreads them over -pool-size")
+	flag.BoolVar(&FIND_STREAM_LIMIT, "find-stream-limit", false, "instead of -n requests, ramp concurrent requests on one connection until they queue for streams, and report the server's effective stream limit")
+	flag.IntVar(&STREAM_LIMIT_MAX, "stream-limit-max", 1024, "highest concurrency -find-stream-limit ramps to")
+	flag.DurationVar(&STREAM_LIMIT_STEP, "stream-limit-step", 5*time.Second, "how long -find-stream-limit runs each concurrency")
+	flag.StringVar(&LB_POLICY, "lb-policy", "", "balance each connection over every DNS address of its -url host: pick_first or round_robin; empty dials the url as given")
+	flag.BoolVar(&WAIT_FOR_READY, "wait-for-ready", false, "queue requests while their connection is reconnecting instead of failing them at once; the wait is reported apart from latency")
+	flag.DurationVar(&BACKOFF_BASE_DELAY, "backoff-base-delay", time.Second, "delay before the first reconnect after a failed connection")
+	flag.Float64Var(&BACKOFF_MULTIPLIER, "backoff-multiplier", 1.6, "factor the reconnect delay grows by after each further failure")
+	flag.Float64Var(&BACKOFF_JITTER, "backoff-jitter", 0.2, "random fraction each reconnect delay is spread by")
+	flag.DurationVar(&BACKOFF_MAX_DELAY, "backoff-max-delay", 120*time.Second, "upper bound of the reconnect delay")
+	flag.DurationVar(&MIN_CONNECT_TIMEOUT, "min-connect-timeout", 20*time.Second, "least time a connection attempt is given before it counts as failed")
+	flag.StringVar(&COMPRESS, "compress", "", "compress requests, and so responses, with this grpc compressor: gzip or none")
+	flag.BoolVar(&COMPRESS_COMPARE, "compress-compare", false, "send every other request uncompressed and report the latency and size difference -compress makes")
+	flag.IntVar(&MAX_RECV_MSG_SIZE, "max-recv-msg-size", 0, "largest response in bytes the client accepts; 0 keeps oasis-core's default")
+	flag.IntVar(&MAX_SEND_MSG_SIZE, "max-send-msg-size", 0, "largest request in bytes the client sends; 0 keeps oasis-core's default")
+	flag.DurationVar(&KEEPALIVE_TIME, "keepalive-time", 0, "send keepalive pings after this long without activity; 0 leaves grpc's default of none")
+	flag.DurationVar(&KEEPALIVE_TIMEOUT, "keepalive-timeout", 20*time.Second, "close the connection if a keepalive ping isn't acked within this")
+	flag.BoolVar(&DEBUG, "debug", false, "log every unary call's method, parameters, encoded sizes and error to stderr")
+	flag.BoolVar(&TCP_INFO, "tcp-info", false, "on linux, read TCP_INFO off every connection's socket and report RTT and retransmits")
+	flag.StringVar(&DUMP_DIR, "dump-dir", "", "with -debug, write the encoded request and raw response of every failed call to files in this directory")
+	flag.DurationVar(&HEDGE_AFTER, "hedge-after", 0, "send a request again, to the next endpoint or connection, if it hasn't answered within this, and keep whichever answers first; 0 for no hedging")
+	flag.IntVar(&RETRY_MAX_ATTEMPTS, "retry-max-attempts", 0, "let grpc retry failed calls up to this many attempts in all (2-5) per its retry policy; 0 for no retries")
+	flag.StringVar(&RETRY_CODES, "retry-codes", "UNAVAILABLE", "comma separated status codes that -retry-max-attempts retries")
+	flag.DurationVar(&RETRY_INITIAL_BACKOFF, "retry-initial-backoff", 100*time.Millisecond, "upper bound of the random delay before the first retry")
+	flag.DurationVar(&RETRY_MAX_BACKOFF, "retry-max-backoff", time.Second, "cap on that bound as it grows")
+	flag.Float64Var(&RETRY_BACKOFF_MULTIPLIER, "retry-backoff-multiplier", 2, "factor the bound grows by after each retry")
+	flag.Var(&HEADERS, "header", "key=value metadata to send with every call; repeat for several")
+	flag.StringVar(&IP_FAMILY, "ip-family", "auto", "address family to connect over: 4, 6, or auto for whatever the resolver and dialer pick")
+	flag.Var(&CA_CERTS, "cacert", "PEM file of CA certificates to trust besides the system's, for gateways with a private CA; repeatable")
+	flag.StringVar(&CERT_FILE, "cert", "", "PEM client certificate for gateways that require mutual TLS; needs -key")
+	flag.StringVar(&KEY_FILE, "key", "", "PEM private key of -cert")
+	flag.StringVar(&SNI, "sni", "", "TLS server name to present and verify instead of the -url host, e.g. to reach one machine by IP behind an SNI-routing proxy")
+	flag.StringVar(&TLS_MIN_VERSION, "tls-min-version", "", "lowest TLS version to offer: 1.0, 1.1, 1.2 or 1.3; empty for go's default")
+	flag.StringVar(&TLS_MAX_VERSION, "tls-max-version", "", "highest TLS version to offer, as -tls-min-version")
+	flag.StringVar(&TLS_CIPHERS, "tls-ciphers", "", "comma separated cipher suites to offer up to TLS 1.2, by go's names, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256; TLS 1.3 suites aren't configurable")
+	flag.StringVar(&PIN_CERT, "pin-cert", "", "fail the handshake unless the server's certificate has this sha256 fingerprint, in hex with or without colons; comma separate several for a rotation")
+	flag.BoolVar(&INSECURE_SKIP_VERIFY, "insecure-skip-verify", false, "don't verify the server's certificate, for labs with self-signed ones; the report says so")
+	flag.BoolVar(&PLAINTEXT, "plaintext", false, "dial without TLS, for local devnets and port-forwarded nodes")
+	flag.StringVar(&PROXY, "proxy", "", "reach the endpoints through socks5://[user:pass@]host:port or an http://host:port CONNECT proxy; empty honors HTTPS_PROXY and NO_PROXY")
+	flag.BoolVar(&LOG_STATE, "log-state", false, "print every connectivity state change (CONNECTING, READY, TRANSIENT_FAILURE, ...) of every connection with a timestamp")
+	flag.BoolVar(&KEEPALIVE_PERMIT_WITHOUT_STREAM, "keepalive-permit-without-stream", false, "send keepalive pings even with no rpcs in flight")
+	flag.BoolVar(&RESOLVE_ALL, "resolve-all", false, "send requests to every A/AAAA record of each -url host separately and report each backend")
+	flag.StringVar(&POOL_ASSIGN, "pool-assign", "round-robin", "how -conn-mode pool picks a connection: round-robin, or least-loaded (fewest requests in flight)")
+	flag.IntVar(&POOL_SIZE, "pool-size", 8, "connections per endpoint for -conn-mode pool")
+	flag.StringVar(&CAPTURE_METADATA, "capture-metadata", "", "comma separated response header/trailer names to record and summarize; empty for all but the ones that never vary")
+	flag.IntVar(&SLOWEST, "slowest", 10, "list this many of the slowest requests with their phases, sizes and connection")
+	flag.DurationVar(&APDEX_THRESHOLD, "apdex-threshold", 0, "target latency T to compute an apdex score per call with, e.g. 250ms; 0 for none")
+	flag.StringVar(&POSTGRES, "postgres", "", "postgres dsn to write this run and every request to, creating the tables if needed")
+	flag.StringVar(&SQLITE, "sqlite", "", "sqlite database to append this run and every request to; see the report subcommand")
+	flag.StringVar(&REPORT, "report", "", "file to write a self-contained html report with charts to")
+	flag.StringVar(&OUT, "out", "", "file for the -output json or markdown summary; stdout if empty, with the text output moved to stderr")
+	flag.StringVar(&CSV, "csv", "", "file to write one row per request to as they finish")
+	flag.StringVar(&OUT_JSONL, "out-jsonl", "", "file to write each request to as a json line as soon as it finishes")
+	flag.StringVar(&METRICS_ADDR, "metrics-addr", "", "address to serve prometheus /metrics on during the run, e.g. :9100")
+	flag.StringVar(&PUSHGATEWAY, "pushgateway", "", "prometheus pushgateway url to push the final aggregates to")
+	flag.StringVar(&JOB, "job", "grpc-test", "job name for -pushgateway")
+	flag.StringVar(&RUN_ID, "run-id", "", "label identifying this run in pushed metrics and stored results; defaults to the start time, host and pid")
+	flag.StringVar(&STATSD, "statsd", "", "statsd host:port to send per-request timings and counters to over udp")
+	flag.StringVar(&STATSD_PREFIX, "statsd-prefix", "grpctest", "metric name prefix for -statsd")
+	flag.StringVar(&STATSD_FORMAT, "statsd-format", "statsd", "statsd, or dogstatsd to send the call and error code as tags")
+	flag.StringVar(&INFLUX, "influx", "", "influx line protocol output for per-interval aggregates: a file to append to, or an http(s) write url")
+	flag.DurationVar(&INFLUX_INTERVAL, "influx-interval", 10*time.Second, "aggregation interval for -influx")
+	flag.StringVar(&OTLP_ENDPOINT, "otlp-endpoint", "", "opentelemetry collector otlp/http base url, e.g. http://localhost:4318, to export traces and metrics to")
+	flag.BoolVar(&OTLP_TRACES, "otlp-traces", true, "export a trace per request to -otlp-endpoint")
+	flag.BoolVar(&OTLP_METRICS, "otlp-metrics", true, "export request, error and latency metrics to -otlp-endpoint")
+	flag.DurationVar(&OTLP_METRICS_INTERVAL, "otlp-metrics-interval", 10*time.Second, "export interval for -otlp-metrics")
+	flag.DurationVar(&INTERVAL, "interval", 0, "print a one line summary (requests, errors, p99 per phase) this often during the run, 0 for none")
+	flag.BoolVar(&PROGRESS, "progress", false, "show completed/total, rate and ETA on stderr")
+	flag.BoolVar(&TUI, "tui", false, "show a live dashboard instead of printing every request")
+	flag.Int64Var(&SEED, "seed", 0, "seed for the random rounds/heights, to request the same ones across runs; 0 picks one and prints it")
+	flag.StringVar(&DISTRIBUTION, "distribution", "uniform", "how random rounds/heights are picked: uniform, zipf (few rounds get most requests), hotspot, recent, or head (always the latest, followed as it moves)")
+	flag.Float64Var(&ZIPF_S, "zipf-s", 1.1, "zipf exponent, > 1; higher concentrates more traffic on the top rounds")
+	flag.Float64Var(&HOT_FRACTION, "hot-fraction", 0.01, "fraction of the range that is hot for -distribution hotspot")
+	flag.Float64Var(&HOT_TRAFFIC, "hot-traffic", 0.9, "fraction of requests that go to the hot rounds for -distribution hotspot")
+	flag.Uint64Var(&RECENT_WINDOW, "recent-window", 10_000, "number of latest rounds/heights that count as recent for -distribution recent")
+	flag.Float64Var(&RECENT_TRAFFIC, "recent-traffic", 0.9, "fraction of requests within -recent-window for -distribution recent; the rest go to deeper history")
+	flag.StringVar(&ROUNDS_FILE, "rounds-file", "", "file of rounds/heights to replay in order instead of random sampling, one per line; - for stdin")
 	flag.Parse()
 
-	start := time.Now()
+	if SEED == 0 {
+		SEED = time.Now().UnixNano()
+	}
+	rand.Seed(SEED)
+	if RUN_ID == "" {
+		// the host and pid keep runs started in the same second, on several
+		// load hosts or against one -sqlite file, from sharing an id
+		host, _ := os.Hostname()
+		RUN_ID = fmt.Sprintf("%s-%s-%d", time.Now().UTC().Format("20060102T150405Z"), host, os.Getpid())
+	}
+	if err := ParseRuntime(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := CheckProxy(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := CheckDebug(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := CheckTcpInfo(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := CheckCaCerts(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := CheckClientCert(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := CheckTlsConfig(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := CheckPinCert(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := CheckRetry(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := CheckIpFamily(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := CheckUnixTargets(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	switch flag.Arg(0) {
+	case "watch":
+		SetupGrpcOpts()
+		if err := RunWatch(context.Background()); err != nil {
+			fmt.Println("Watch error:", err)
+			os.Exit(1)
+		}
+		PrintStateTransitions()
+		return
+	case "soak":
+		SetupGrpcOpts()
+		if err := RunSoak(context.Background()); err != nil {
+			fmt.Println("Soak error:", err)
+			os.Exit(1)
+		}
+		PrintStateTransitions()
+		return
+	case "watch-events":
+		SetupGrpcOpts()
+		if err := RunWatchEvents(context.Background()); err != nil {
+			fmt.Println("Watch events error:", err)
+			os.Exit(1)
+		}
+		PrintStateTransitions()
+		return
+	case "follow":
+		SetupGrpcOpts()
+		if err := RunFollow(context.Background()); err != nil {
+			fmt.Println("Follow error:", err)
+			os.Exit(1)
+		}
+		PrintStateTransitions()
+		return
+	case "report":
+		if err := RunReport(flag.Args()[1:]); err != nil {
+			fmt.Println("Report error:", err)
+			os.Exit(2)
+		}
+		return
+	case "diff":
+		if flag.NArg() != 3 {
+			fmt.Println("usage: diff baseline.json current.json")
+			os.Exit(2)
+		}
+		if err := RunDiff(flag.Arg(1), flag.Arg(2)); err != nil {
+			fmt.Println("Diff error:", err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	if err := CheckDistribution(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if OUTPUT != "text" && OUTPUT != "json" && OUTPUT != "markdown" {
+		fmt.Println("bad -output", OUTPUT, "(expected text, json or markdown)")
+		os.Exit(2)
+	}
+	SeparateReportOutput()
+	if err := CheckAddressSampling(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if FIND_STREAM_LIMIT {
+		if STREAM_LIMIT_MAX < 1 {
+			fmt.Println("bad -stream-limit-max", STREAM_LIMIT_MAX)
+			os.Exit(2)
+		}
+		if len(URLS) > 1 || RESOLVE_ALL || ALL_RUNTIMES || RANGE_TO != 0 {
+			fmt.Println("-find-stream-limit needs a single -url and can't be combined with -resolve-all, -all-runtimes or -from/-to")
+			os.Exit(2)
+		}
+		CONN_MODE = "shared"
+	}
+	if err := CheckConnMode(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := CheckCompress(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := CheckLbPolicy(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if INFLUX != "" && INFLUX_INTERVAL <= 0 {
+		fmt.Println("-influx-interval must be positive")
+		os.Exit(2)
+	}
+	if OTLP_ENDPOINT != "" && OTLP_METRICS && OTLP_METRICS_INTERVAL <= 0 {
+		fmt.Println("-otlp-metrics-interval must be positive")
+		os.Exit(2)
+	}
+	autoRange := MAX_ROUND == 0 || MAX_HEIGHT == 0
+	if MAX_ROUND == 0 {
+		MAX_ROUND = math.MaxUint64
+	}
+	if MAX_HEIGHT == 0 {
+		MAX_HEIGHT = math.MaxUint64
+	}
+	if MIN_ROUND >= MAX_ROUND || MIN_HEIGHT >= MAX_HEIGHT {
+		fmt.Println("-min-round/-min-height must be below -max-round/-max-height")
+		os.Exit(2)
+	}
+	if RANGE_TO != 0 {
+		if RANGE_TO < RANGE_FROM {
+			fmt.Println("-to", RANGE_TO, "is before -from", RANGE_FROM)
+			os.Exit(2)
+		}
+		if ALL_RUNTIMES {
+			fmt.Println("-from/-to and -all-runtimes can't be combined")
+			os.Exit(2)
+		}
+		if WINDOW < 1 {
+			fmt.Println("-window must be at least 1")
+			os.Exit(2)
+		}
+		NUM_REQUESTS = int(RANGE_TO - RANGE_FROM + 1)
+	}
+
+	call, ok := CALLS[CALL]
+	if !ok {
+		fmt.Println("unknown call:", CALL, "(expected one of", CallNames()+")")
+		os.Exit(2)
+	}
+	if call.Dangerous && !ALLOW_DANGEROUS {
+		fmt.Println("call", CALL, "can put heavy load on the node or change chain state; pass -allow-dangerous if you really mean it")
+		os.Exit(2)
+	}
+	if HEDGE_AFTER > 0 {
+		call.F = Hedged(call.F)
+	}
+
+	if SKIP_ROUNDS_FILE != "" {
+		if err := LoadSkipRoundsFile(); err != nil {
+			fmt.Println("Skip rounds file error:", err)
+			os.Exit(1)
+		}
+	}
+	if ROUNDS_FILE != "" {
+		if err := LoadRoundsFile(); err != nil {
+			fmt.Println("Rounds file error:", err)
+			os.Exit(1)
+		}
+		nSet := false
+		flag.Visit(func(f *flag.Flag) { nSet = nSet || f.Name == "n" })
+		if !nSet {
+			NUM_REQUESTS = len(replayRounds)
+		}
+		call.Params = NextRound
+	}
+
 	SetupGrpcOpts()
-	num_errors := CallSimultaneous(
-		context.Background(),
-		GetSapphireRound,
-		RandomSapphireHeight,
-	)
+	if err := DetectApiVersion(context.Background(), call.ApiVersioned); err != nil {
+		fmt.Println("API version error:", err)
+		os.Exit(1)
+	}
+	fmt.Println("API version:", API_VERSION)
+	fmt.Println("Seed:", SEED)
+	fmt.Println("Conn mode:", CONN_MODE)
+	if RESOLVE_ALL {
+		if err := ResolveAll(context.Background()); err != nil {
+			fmt.Println("Resolve error:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Backends:", strings.Join(URLS, ", "))
+	}
+	// -all-runtimes clamps and sets up per runtime
+	if !ALL_RUNTIMES {
+		if err := ClampToRetained(context.Background(), call); err != nil {
+			if autoRange {
+				fmt.Println("Retained window error, pass -max-round and -max-height to sample without it:", err)
+				os.Exit(1)
+			}
+			fmt.Println("Retained window error, using the full ranges:", err)
+		}
+	}
+	if DISTRIBUTION == "head" {
+		if err := StartHeadWatcher(context.Background()); err != nil {
+			fmt.Println("Head watcher error:", err)
+			os.Exit(1)
+		}
+	}
+	var nodeStatus *NodeStatus
+	if NODE_STATUS {
+		var err error
+		if nodeStatus, err = GetNodeStatus(context.Background()); err != nil {
+			fmt.Println("Node status error:", err)
+		}
+	}
+	if call.Setup != nil && !ALL_RUNTIMES {
+		if err := call.Setup(context.Background()); err != nil {
+			fmt.Println("Setup error:", err)
+			os.Exit(1)
+		}
+	}
+
+	if code := run(call, nodeStatus); code != 0 {
+		os.Exit(code)
+	}
+}
+
+// opens the sinks, runs the requests and reports; returns the exit code
+// rather than exiting so the sinks' deferred closes still run
+func run(call Call, nodeStatus *NodeStatus) int {
+	if CSV != "" {
+		if err := OpenCsv(); err != nil {
+			fmt.Println("CSV error:", err)
+			return 1
+		}
+		defer CloseCsv()
+	}
+	if METRICS_ADDR != "" {
+		StartMetrics()
+	}
+	if OTLP_ENDPOINT != "" && OTLP_TRACES {
+		OpenTracing()
+		defer CloseTracing()
+	}
+	if OTLP_ENDPOINT != "" && OTLP_METRICS {
+		OpenOtlpMetrics()
+		defer CloseOtlpMetrics()
+	}
+	if INFLUX != "" {
+		OpenInflux()
+		defer CloseInflux()
+	}
+	if STATSD != "" {
+		if err := OpenStatsd(); err != nil {
+			fmt.Println("StatsD error:", err)
+			return 1
+		}
+	}
+	if OUT_JSONL != "" {
+		if err := OpenJsonl(); err != nil {
+			fmt.Println("JSONL error:", err)
+			return 1
+		}
+		defer CloseJsonl()
+	}
+	if SQLITE != "" {
+		if err := OpenSqlite(); err != nil {
+			fmt.Println("SQLite error:", err)
+			return 1
+		}
+		defer CloseSqlite()
+	}
+	if POSTGRES != "" {
+		if err := OpenPostgres(); err != nil {
+			fmt.Println("Postgres error:", err)
+			return 1
+		}
+		defer ClosePostgres()
+	}
+
+	if INTERVAL > 0 {
+		StartIntervalSummaries()
+	}
+	if TUI {
+		StartTui()
+	} else if PROGRESS {
+		StartProgress()
+	}
+	StartSeries()
+	StartClientUsage()
+	start := time.Now()
+	num_requests, num_errors := NUM_REQUESTS, 0
+	if ALL_RUNTIMES {
+		num_requests, num_errors = RunAllRuntimes(context.Background(), call)
+	} else if FIND_STREAM_LIMIT {
+		num_requests, num_errors = FindStreamLimit(context.Background(), call.F, call.Params)
+	} else if RANGE_TO != 0 {
+		num_errors = CallRange(context.Background(), call.F, RANGE_FROM, RANGE_TO)
+	} else {
+		num_errors = CallSimultaneous(
+			context.Background(),
+			call.F,
+			call.Params,
+		)
+	}
 	time_taken := (time.Now().Sub(start))
+	ClosePools()
+	usage := StopClientUsage()
+	if TUI {
+		StopTui()
+	} else if PROGRESS {
+		StopProgress()
+	}
+	if INTERVAL > 0 {
+		StopIntervalSummaries()
+	}
 
+	if INSECURE_SKIP_VERIFY && !PLAINTEXT {
+		fmt.Println("WARNING: server certificates were not verified (-insecure-skip-verify)")
+	}
 	fmt.Println("Total time:", time_taken)
-	fmt.Println("Errors:", num_errors, "/", NUM_REQUESTS)
-	fmt.Println("Rate:", float32(NUM_REQUESTS) / float32(time_taken.Seconds()), "/s")
+	fmt.Println("Errors:", num_errors, "/", num_requests)
+	PrintErrorClasses()
+	PrintRetries()
+	PrintHedging()
+	if knownBadHits != 0 {
+		fmt.Println("Known-bad rounds requested:", knownBadHits, "Errors:", knownBadErrors, "(not counted above)")
+	}
+	fmt.Println("Rate:", float32(num_requests) / float32(time_taken.Seconds()), "/s")
+	latencies.Print()
+	PrintQueued()
+	PrintApdex()
+	PrintConnTimes()
+	PrintDisconnects()
+	PrintStateTransitions()
+	PrintReconnects()
+	PrintTcpInfo()
+	PrintTlsParams()
+	PrintCompression()
+	PrintBandwidth(time_taken)
+	PrintEndpoints(time_taken)
+	PrintPoolConns(time_taken)
+	PrintBackends(time_taken)
+	PrintIpFamilies(time_taken)
+	PrintMetadata()
+	usage.Print()
+	PrintSlowest()
+	assertions := CheckAssertions(num_requests, num_errors, time_taken)
+	PrintAssertions(assertions)
+	if nodeStatus != nil {
+		fmt.Println("Node:", nodeStatus.String())
+	}
+	if OUTPUT != "text" || PUSHGATEWAY != "" || REPORT != "" || SQLITE != "" {
+		summary := NewSummary(num_requests, num_errors, time_taken, nodeStatus, usage)
+		var err error
+		switch OUTPUT {
+		case "json":
+			err = summary.Write()
+		case "markdown":
+			err = summary.WriteMarkdown()
+		}
+		if err != nil {
+			fmt.Println("Output error:", err)
+		}
+		if PUSHGATEWAY != "" {
+			if err := PushSummary(summary); err != nil {
+				fmt.Println("Pushgateway error:", err)
+			}
+		}
+		if REPORT != "" {
+			if err := WriteHtmlReport(summary); err != nil {
+				fmt.Println("Report error:", err)
+			}
+		}
+		if SQLITE != "" {
+			if err := WriteSqliteRun(summary); err != nil {
+				fmt.Println("SQLite error:", err)
+			}
+		}
+	}
+
+	if call.Check != nil {
+		if err := call.Check(); err != nil {
+			fmt.Println("Check failed:", err)
+			return 1
+		}
+	}
+	if AssertionsFailed(assertions) {
+		return 1
+	}
+	return 0
 }
 
 type ThreadStatus struct {
@@ -72,6 +754,24 @@ type ThreadStatus struct {
 	err error
 	msg string
 	times ApiTimes
+	bytes int64 // received payload, counted by Connect's stats handler
+	rawBytes int64 // the same after decompressing
+	compressor string // set by Connect with -compress
+	queued time.Duration // rpcs waiting for a ready connection, counted by Connect's stats handler
+	calls int // rpcs with -retry-max-attempts, likewise
+	retries int // their attempts after the first
+	hedged bool // a second request was sent with -hedge-after
+	hedgeWon bool // and this is its status
+	rpcs map[string]*RpcSize // the same per grpc method
+	endpoint string // set by Connect
+	conn string // local->remote address, set by Connect's stats handler
+	metadata map[string]string // response headers and trailers, likewise
+	connTimes ConnTimes // likewise
+	poolConn string // endpoint#index with -conn-mode shared or pool
+	peer string // backend address the rpcs went to, set by Connect's stats handler
+	start time.Time // set by Connect
+	traceID string // set by Connect when tracing
+	spanID string
 }
 
 type ApiTimes struct {
@@ -80,11 +780,182 @@ type ApiTimes struct {
 	GetTransactions time.Duration
 	GetEvents time.Duration
 	Parse time.Duration
+	Query time.Duration
+	GetRuntimeState time.Duration
+	SyncGet time.Duration
+	SyncGetPrefixes time.Duration
+	Web3GetBlock time.Duration
+	Web3GetLogs time.Duration
+	NexusGetBlock time.Duration
+	Ready time.Duration // dial-only
+	Hedge time.Duration // -hedge-after, when the hedge answered first
+	Close time.Duration
 }
 
+type Phase struct {
+	Name string
+	Duration time.Duration
+}
+
+// every phase after Connect, in the order they are run
+func (t *ApiTimes) Phases() []Phase {
+	return []Phase{
+		{"Hedge", t.Hedge},
+		{"Ready", t.Ready},
+		{"GetRuntimeState", t.GetRuntimeState},
+		{"GetBlock", t.GetBlock},
+		{"GetTransactions", t.GetTransactions},
+		{"GetEvents", t.GetEvents},
+		{"ExtractRound", t.Parse},
+		{"Query", t.Query},
+		{"SyncGet", t.SyncGet},
+		{"SyncGetPrefixes", t.SyncGetPrefixes},
+		{"Web3GetBlock", t.Web3GetBlock},
+		{"Web3GetLogs", t.Web3GetLogs},
+		{"NexusGetBlock", t.NexusGetBlock},
+		{"Close", t.Close},
+	}
+}
+
+// phases that were not run (or failed before finishing) are left out
 func (t *ApiTimes) String() string {
-	return fmt.Sprintf("Connect: %s, GetBlock: %s, GetTransactions: %s, GetEvents: %s, ExtractRound: %s",
-	                   t.Connect.String(), t.GetBlock.String(), t.GetTransactions.String(), t.GetEvents.String(), t.Parse.String())
+	s := "Connect: " + t.Connect.String()
+	for _, phase := range t.Phases() {
+		if phase.Duration != 0 {
+			s += ", " + phase.Name + ": " + phase.Duration.String()
+		}
+	}
+	return s
+}
+
+type Call struct {
+	F       func(context.Context, uint64) ThreadStatus
+	Params  func() uint64
+	Setup   func(context.Context) error // optional; runs once before the timed requests
+	Check   func() error                // optional; validates the collected results after the run
+	Rounds  bool                        // samples runtime rounds
+	Heights bool                        // samples consensus heights
+
+	ApiVersioned bool // needs -api-version auto resolved before the run
+
+	Dangerous bool // refused unless -allow-dangerous
+}
+
+// call functions selectable with -call
+var CALLS = map[string]Call{
+	"getblock":              {F: GetSapphireRound, Params: RandomSapphireHeight, Rounds: true, ApiVersioned: true},
+	"core-parameters":       {F: QueryCoreParameters, Params: RandomSapphireHeight, Rounds: true},
+	"accounts-balances":     {F: QueryAccountsBalances, Params: RandomSapphireHeight, Setup: LoadAccountAddresses, Rounds: true},
+	"staking-account":       {F: GetStakingAccount, Params: RandomConsensusHeight, Setup: LoadStakingAddresses, Heights: true},
+	"delegations":           {F: GetDelegationsFor, Params: RandomConsensusHeight, Setup: LoadStakingAddresses, Heights: true},
+	"debonding-delegations": {F: GetDebondingDelegationsFor, Params: RandomConsensusHeight, Setup: LoadStakingAddresses, Heights: true},
+	"registry-nodes":        {F: GetRegistryNodes, Params: RandomConsensusHeight, Heights: true},
+	"registry-entities":     {F: GetRegistryEntities, Params: RandomConsensusHeight, Heights: true},
+	"committees":            {F: GetCommittees, Params: RandomConsensusHeight, Heights: true},
+	"governance-proposals":  {F: GetProposals, Params: RandomConsensusHeight, Heights: true},
+	"governance-votes":      {F: GetVotes, Params: RandomConsensusHeight, Heights: true},
+	"epoch":                 {F: GetEpoch, Params: RandomConsensusHeight, Heights: true, Check: CheckEpochsMonotonic},
+	"roothash-state":        {F: GetRoothashState, Params: RandomConsensusHeight, Heights: true},
+	"txs":                   {F: GetSapphireTransactions, Params: RandomSapphireHeight, Rounds: true},
+	"events":                {F: GetSapphireEvents, Params: RandomSapphireHeight, Rounds: true},
+	"tx-by-hash":            {F: GetTxByHash, Params: RandomHarvestedRound, Setup: HarvestTxHashes, Rounds: true},
+	"state-to-genesis":      {F: GetStateToGenesis, Params: RandomConsensusHeight, Heights: true, Dangerous: true},
+	"genesis-document":      {F: GetGenesisDocument, Params: NoParams},
+	"chain-context":         {F: GetChainContext, Params: NoParams},
+	"storage-sync":          {F: SyncStorage, Params: RandomSapphireHeight, Rounds: true},
+	"submit":                {F: SubmitTransaction, Params: NextTransaction, Setup: LoadTransactions, Dangerous: true},
+	"evm-simulate":          {F: SimulateEvmCall, Params: RandomSapphireHeight, Setup: ParseEvmCall, Rounds: true},
+	"estimate-gas":          {F: EstimateEvmGas, Params: RandomSapphireHeight, Setup: ParseEvmCall, Rounds: true},
+	"keymanager-status":     {F: GetKeyManagerStatus, Params: RandomConsensusHeight, Setup: LoadKeyManagerID, Heights: true},
+	"signer-nonce":          {F: GetSignerNonce, Params: RandomConsensusHeight, Setup: LoadStakingAddresses, Heights: true},
+	"raw":                   {F: InvokeRaw, Params: NoParams, Setup: LoadRawPayload},
+	"health":                {F: CheckHealth, Params: NoParams},
+	"health-watch":          {F: WatchHealth, Params: NoParams},
+	"dial-only":             {F: DialOnly, Params: NoParams, Setup: CheckDialOnly},
+	"web3-compare":          {F: CompareWeb3, Params: RandomSapphireHeight, Setup: CheckWeb3Url, Rounds: true},
+	"nexus-compare":         {F: CompareNexus, Params: RandomSapphireHeight, Setup: CheckNexusFreshness, Rounds: true},
+}
+
+func CallNames() string {
+	names := make([]string, 0, len(CALLS))
+	for name := range CALLS {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// dials the next endpoint, recording it in status.endpoint, the time taken
+// in status.times.Connect and the bytes received over the connection in
+// status.bytes. with -conn-mode shared or pool it hands out an existing
+// connection instead; callers Release it rather than closing it
+func Connect(ctx context.Context, status *ThreadStatus) (*grpc.ClientConn, error) {
+	start := time.Now()
+	status.start = start
+	status.compressor = pickCompressor()
+	if CONN_MODE != "per-request" {
+		status.endpoint = NextEndpoint()
+		if slot, ok := ctx.Value(statusSlotKey{}).(*statusSlot); ok {
+			slot.status = status
+		}
+		StartTrace(status)
+		conn, label, err := pooledConn(status.endpoint)
+		status.poolConn = label
+		status.times.Connect = time.Since(start)
+		return conn, err
+	}
+	counter := &byteCounter{status: status}
+	status.endpoint = NextEndpoint()
+	opts := append(dialOpts[:len(dialOpts):len(dialOpts)], grpc.WithStatsHandler(counter))
+	opts = append(opts, connTimingDialOpts(counter)...)
+	opts = append(opts, TraceDialOpts(status)...)
+	opts = append(opts, CompressDialOpts(status)...)
+	opts = append(opts, endpointDialOpts(status.endpoint)...)
+	conn, err := oasisGrpc.Dial(lbTarget(status.endpoint), opts...)
+	status.times.Connect = time.Since(start)
+	if err == nil {
+		WatchState(conn, status.endpoint)
+	}
+	return conn, err
+}
+
+// runtimes selectable by name with -runtime
+var RUNTIMES = map[string]string{
+	"sapphire":         "000000000000000000000000000000000000000000000000f80306c9858e7279",
+	"emerald":          "000000000000000000000000000000000000000000000000e2eaa99fc008f87f",
+	"cipher":           "000000000000000000000000000000000000000000000000e199119c992377cb",
+	"sapphire-testnet": "000000000000000000000000000000000000000000000000a6d1e3ebf60dff6c",
+	"emerald-testnet":  "00000000000000000000000000000000000000000000000072c8215e60d5bca7",
+	"cipher-testnet":   "0000000000000000000000000000000000000000000000000000000000000000",
+}
+
+// parses -runtime, a name from RUNTIMES or a hex namespace, into RUNTIME_ID
+// and RUNTIME_NAME (empty for unknown runtimes)
+func ParseRuntime() error {
+	id, ok := RUNTIMES[RUNTIME]
+	if ok {
+		RUNTIME_NAME = RUNTIME
+	} else {
+		id = RUNTIME
+		for name, known := range RUNTIMES {
+			if known == id {
+				RUNTIME_NAME = name
+			}
+		}
+	}
+	if err := RUNTIME_ID.UnmarshalHex(id); err != nil {
+		return fmt.Errorf("bad -runtime '%s', expected a hex namespace or one of %s: %w", RUNTIME, RuntimeNames(), err)
+	}
+	return nil
+}
+
+func RuntimeNames() string {
+	names := make([]string, 0, len(RUNTIMES))
+	for name := range RUNTIMES {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
 }
 
 // returns number of failed requests
@@ -92,60 +963,80 @@ func CallSimultaneous(ctx context.Context,
 					  call_f func(context.Context, uint64) ThreadStatus,
 					  parameter_f func() uint64,
 				     ) (num_errors int) {
-	wg := sync.WaitGroup{}
 	ch := make(chan ThreadStatus, NUM_REQUESTS)
 
 	// start threads
-	for i := 0; i < NUM_REQUESTS; i++ {
-		subctx, cancel := context.WithTimeout(ctx, TIMEOUT)
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			ch <- call_f(subctx, parameter_f())
-			cancel()
-		}()
-		time.Sleep(DELAY)
-	}
-
-	// wait for them to finish
-	wg.Wait()
+	go func() {
+		for i := 0; i < NUM_REQUESTS; i++ {
+			subctx, cancel := context.WithTimeout(WithStatusSlot(ctx), TIMEOUT)
+			// drawn here rather than in the thread so a -seed gives the same order
+			height := parameter_f()
+			go func() {
+				ch <- track(func() ThreadStatus { return call_f(subctx, height) })
+				cancel()
+			}()
+			time.Sleep(DELAY)
+		}
+	}()
 
-	// print statuses
+	// print statuses as they finish, so streaming outputs stay current on
+	// long runs
 	for i := 0; i < NUM_REQUESTS; i++ {
 		status, _ := <- ch
-		// if loglevel=timing
-		fmt.Println(status.times.String())
-		// if loglevel=blockdata
-		fmt.Println(status.msg)
-		if status.err != nil {
-			fmt.Printf("thread %d: %s\n", status.ID, status.err)
+		if CollectStatus(status) {
 			num_errors += 1
 		}
 	}
 	return num_errors
 }
 
-func RandomSapphireHeight() uint64 { // 500_000 to 900_000
-	return 500_000 + (rand.Uint64() % 400_000)
+// ranges sampled by the Random*Height functions, [min, max); set by
+// -min-round etc. and narrowed by ClampToRetained to what the node still
+// has. a max of 0 means up to the latest block, so the defaults cover the
+// whole retained window of whichever network -url points at
+var (
+	MIN_ROUND uint64
+	MAX_ROUND uint64
+	MIN_HEIGHT uint64
+	MAX_HEIGHT uint64
+)
+
+func RandomSapphireHeight() uint64 {
+	if DISTRIBUTION == "head" {
+		return HeadRound()
+	}
+	return SampleRange(MIN_ROUND, MAX_ROUND)
+}
+
+func RandomConsensusHeight() uint64 {
+	if DISTRIBUTION == "head" {
+		return HeadHeight()
+	}
+	return SampleRange(MIN_HEIGHT, MAX_HEIGHT)
+}
+
+// for calls that don't take a height
+func NoParams() uint64 {
+	return 0
 }
 
 func GetSapphireRound(ctx context.Context, height uint64) ThreadStatus {
+	if API_VERSION == "cobalt" {
+		return GetCobaltRound(ctx, height)
+	}
 	status := ThreadStatus{ID: height, times: ApiTimes{}}
-	start := time.Now()
-	conn, err := oasisGrpc.Dial(URL, dialOpts...)
+	conn, err := Connect(ctx, &status)
 	if err != nil {
 		status.err = err
 		return status
 	}
+	defer Release(conn)
 
 	client := runtime.NewRuntimeClient(conn)
-	sapphire := &common.Namespace{}
-	sapphire.UnmarshalText(([]byte)("000000000000000000000000000000000000000000000000f80306c9858e7279"))
-	status.times.Connect = time.Since(start)
 
-	start = time.Now()
+	start := time.Now()
 	getBlockRequest := &runtime.GetBlockRequest{
-		RuntimeID: *sapphire,
+		RuntimeID: RUNTIME_ID,
 		Round: height,
 	}
 	block, err := client.GetBlock(ctx, getBlockRequest)
@@ -157,7 +1048,7 @@ func GetSapphireRound(ctx context.Context, height uint64) ThreadStatus {
 
 	start = time.Now()
 	getTransactionsRequest := &runtime.GetTransactionsRequest{
-		RuntimeID: *sapphire,
+		RuntimeID: RUNTIME_ID,
 		Round: height,
 	}
 	txs, err := client.GetTransactionsWithResults(ctx, getTransactionsRequest)
@@ -168,7 +1059,7 @@ func GetSapphireRound(ctx context.Context, height uint64) ThreadStatus {
 
 	start = time.Now()
 	getEventsRequest := &runtime.GetEventsRequest{
-		RuntimeID: *sapphire,
+		RuntimeID: RUNTIME_ID,
 		Round: height,
 	}
 	events, err := client.GetEvents(ctx, getEventsRequest)
@@ -184,6 +1075,56 @@ func GetSapphireRound(ctx context.Context, height uint64) ThreadStatus {
 	return status
 }
 
+// GetTransactions only, without results
+func GetSapphireTransactions(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	client := runtime.NewRuntimeClient(conn)
+	start := time.Now()
+	txs, err := client.GetTransactions(ctx, &runtime.GetTransactionsRequest{
+		RuntimeID: RUNTIME_ID,
+		Round: height,
+	})
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.times.GetTransactions = time.Since(start)
+	status.msg = fmt.Sprintf("Round: %d, NumTransactions: %d", height, len(txs))
+	return status
+}
+
+// GetEvents only
+func GetSapphireEvents(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	client := runtime.NewRuntimeClient(conn)
+	start := time.Now()
+	events, err := client.GetEvents(ctx, &runtime.GetEventsRequest{
+		RuntimeID: RUNTIME_ID,
+		Round: height,
+	})
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.times.GetEvents = time.Since(start)
+	status.msg = fmt.Sprintf("Round: %d, NumEvents: %d", height, len(events))
+	return status
+}
+
 func TryNexusParseBlock(block *block.Block, blockTxs []*runtime.TransactionWithResults, blockEvents []*runtime.Event) (*nexusRuntime.BlockData, error) {
 	header := nodeapi.RuntimeBlockHeader{
 		Version:        block.Header.Version,
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// error classes listed in the markdown summary
+const MARKDOWN_TOP_ERRORS = 5
+
+// table cells can't hold pipes or newlines
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\n", " ")
+
+// writes compact tables for pasting into issues, to -out or stdout; config
+// lists only the flags that were set
+func (s *Summary) WriteMarkdown() error {
+	var w io.Writer = reportOut
+	if OUT != "" && OUT != "-" {
+		f, err := os.Create(OUT)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	fmt.Fprintf(w, "**%s** against `%s`\n\n", CALL, URL)
+	if s.InsecureSkipVerify {
+		fmt.Fprint(w, "**Warning:** server certificates were not verified (-insecure-skip-verify), this is a lab run, not a production measurement\n\n")
+	}
+	fmt.Fprintln(w, "| flag | value |")
+	fmt.Fprintln(w, "|---|---|")
+	flag.Visit(func(f *flag.Flag) {
+		fmt.Fprintf(w, "| %s | %s |\n", f.Name, markdownEscaper.Replace(redactedFlag(f)))
+	})
+	fmt.Fprintf(w, "| seed | %d |\n", s.Seed)
+	fmt.Fprintf(w, "| conn mode | %s |\n\n", s.ConnMode)
+
+	fmt.Fprintln(w, "| requests | errors | time | rate |")
+	fmt.Fprintln(w, "|---:|---:|---:|---:|")
+	fmt.Fprintf(w, "| %d | %d | %.1fs | %.1f/s |\n\n", s.Requests, s.Errors, s.TotalSeconds, s.Rate)
+
+	fmt.Fprintln(w, "| phase | n | mean ms | p50 ms | p95 ms | p99 ms | max ms | share |")
+	fmt.Fprintln(w, "|---|---:|---:|---:|---:|---:|---:|---:|")
+	row := func(name string, p PercentileSummary) {
+		fmt.Fprintf(w, "| %s | %d | %.1f | %.1f | %.1f | %.1f | %.1f | %.0f%% |\n", name, p.Count, p.Mean, p.P50, p.P95, p.P99, p.Max, 100*p.Share)
+	}
+	for _, name := range latencies.Order {
+		row(name, s.Phases[name])
+	}
+	total := s.Total
+	total.Share = 1
+	row("**Total**", total)
+
+	if len(s.Assertions) != 0 {
+		fmt.Fprintln(w, "\n| assertion | limit | actual | |")
+		fmt.Fprintln(w, "|---|---:|---:|---|")
+		for _, a := range s.Assertions {
+			result := "ok"
+			if !a.Passed {
+				result = "**FAIL**"
+			}
+			fmt.Fprintf(w, "| %s | %s | %s | %s |\n", a.Name, a.Limit, a.Actual, result)
+		}
+	}
+
+	if len(s.ErrorClasses) != 0 {
+		fmt.Fprintln(w, "\n| count | code | message | e.g. rounds |")
+		fmt.Fprintln(w, "|---:|---|---|---|")
+		for i, class := range s.ErrorClasses {
+			if i == MARKDOWN_TOP_ERRORS {
+				break
+			}
+			fmt.Fprintf(w, "| %d | %s | %s | %v |\n", class.Count, class.Code, markdownEscaper.Replace(class.Message), class.Examples)
+		}
+	}
+	return nil
+}
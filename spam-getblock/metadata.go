@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// distinct values kept per key; the rest count as "(other)"
+const MAX_METADATA_VALUES = 20
+
+// headers that are the same on every response, or different on every one,
+// and so say nothing about which server answered
+var noisyMetadata = map[string]bool{
+	"content-type":            true,
+	"date":                    true,
+	"grpc-status":             true,
+	"grpc-message":            true,
+	"grpc-status-details-bin": true,
+	"grpc-encoding":           true,
+	"grpc-accept-encoding":    true,
+}
+
+// which keys to keep: those in -capture-metadata, or every key but the
+// noisy ones when it's empty
+func captureMetadataKey(key string) bool {
+	if CAPTURE_METADATA == "" {
+		return !noisyMetadata[key]
+	}
+	for _, k := range strings.Split(CAPTURE_METADATA, ",") {
+		if strings.EqualFold(strings.TrimSpace(k), key) {
+			return true
+		}
+	}
+	return false
+}
+
+// adds response headers or trailers to status.metadata; called by the
+// stats handler with its lock held
+func captureMetadata(s *ThreadStatus, md metadata.MD) {
+	for key, values := range md {
+		if !captureMetadataKey(key) || strings.HasSuffix(key, "-bin") {
+			continue
+		}
+		if s.metadata == nil {
+			s.metadata = map[string]string{}
+		}
+		s.metadata[key] = strings.Join(values, ",")
+	}
+}
+
+// counts per key and value; only touched by the goroutine collecting
+// statuses
+var metadataValues = map[string]map[string]int{}
+
+func RecordMetadata(s *ThreadStatus) {
+	for key, value := range s.metadata {
+		values, ok := metadataValues[key]
+		if !ok {
+			values = map[string]int{}
+			metadataValues[key] = values
+		}
+		if _, ok := values[value]; !ok && len(values) >= MAX_METADATA_VALUES {
+			value = "(other)"
+		}
+		values[value]++
+	}
+}
+
+// distinct values of each key, most common first, so a mixed fleet behind
+// a load balancer shows up as several server versions
+func PrintMetadata() {
+	if len(metadataValues) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(metadataValues))
+	for key := range metadataValues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fmt.Println("Response metadata:")
+	for _, key := range keys {
+		values := metadataValues[key]
+		sorted := make([]string, 0, len(values))
+		for value := range values {
+			sorted = append(sorted, value)
+		}
+		sort.Slice(sorted, func(i, j int) bool { return values[sorted[i]] > values[sorted[j]] })
+		fmt.Printf("\t%s:", key)
+		for _, value := range sorted {
+			fmt.Printf(" %s (%d)", value, values[value])
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	metricRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpctest_requests_total",
+		Help: "Finished requests.",
+	}, []string{"call"})
+	metricErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpctest_errors_total",
+		Help: "Failed requests by grpc status code.",
+	}, []string{"call", "code"})
+	metricPhaseSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpctest_phase_seconds",
+		Help:    "Latency of each phase of a request, and of the whole request as phase total.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 16),
+	}, []string{"call", "phase"})
+	metricResponseBytes = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpctest_response_bytes",
+		Help:    "Payload bytes received per request.",
+		Buckets: prometheus.ExponentialBuckets(256, 4, 10),
+	}, []string{"call"})
+)
+
+// serves /metrics on -metrics-addr for the rest of the process' life
+func StartMetrics() {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		if err := http.ListenAndServe(METRICS_ADDR, mux); err != nil {
+			fmt.Println("Metrics error:", err)
+		}
+	}()
+	statusSinks = append(statusSinks, ObserveMetrics)
+}
+
+func ObserveMetrics(s *ThreadStatus) {
+	metricRequests.WithLabelValues(CALL).Inc()
+	if s.err != nil {
+		metricErrors.WithLabelValues(CALL, errorCode(s.err)).Inc()
+	}
+	metricPhaseSeconds.WithLabelValues(CALL, "Connect").Observe(s.times.Connect.Seconds())
+	for _, phase := range s.times.Phases() {
+		if phase.Duration != 0 {
+			metricPhaseSeconds.WithLabelValues(CALL, phase.Name).Observe(phase.Duration.Seconds())
+		}
+	}
+	metricPhaseSeconds.WithLabelValues(CALL, "total").Observe(s.times.Total().Seconds())
+	metricResponseBytes.WithLabelValues(CALL).Observe(float64(s.bytes))
+}
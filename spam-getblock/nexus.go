@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	oasisGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	roothash "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	runtime "github.com/oasisprotocol/oasis-core/go/runtime/client/api"
+)
+
+type nexusRuntimeBlock struct {
+	Round           uint64 `json:"round"`
+	Hash            string `json:"hash"`
+	NumTransactions int    `json:"num_transactions"`
+}
+
+type nexusRuntimeBlockList struct {
+	Blocks []nexusRuntimeBlock `json:"blocks"`
+}
+
+type nexusRuntimeStatus struct {
+	LatestBlock uint64 `json:"latest_block"`
+}
+
+// nexus serves mainnet and testnet from separate deployments under the same
+// runtime names
+func NexusRuntime() string {
+	return strings.TrimSuffix(RUNTIME_NAME, "-testnet")
+}
+
+// GET against the nexus api, decoding the json body into v
+func NexusGet(ctx context.Context, v interface{}, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(NEXUS_URL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: http %s", path, rsp.Status)
+	}
+	return json.NewDecoder(rsp.Body).Decode(v)
+}
+
+// prints how far the indexer is behind the node, so rounds it hasn't
+// reached yet aren't mistaken for indexing bugs
+func CheckNexusFreshness(ctx context.Context) error {
+	if NEXUS_URL == "" {
+		return fmt.Errorf("nexus-compare needs -nexus-url, e.g. https://nexus.oasis.io")
+	}
+	if RUNTIME_NAME == "" {
+		return fmt.Errorf("nexus-compare needs a named -runtime, one of %s", RuntimeNames())
+	}
+	var nexusStatus nexusRuntimeStatus
+	if err := NexusGet(ctx, &nexusStatus, "/v1/"+NexusRuntime()+"/status"); err != nil {
+		return err
+	}
+
+	conn, err := oasisGrpc.Dial(URL, dialOpts...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	latest, err := runtime.NewRuntimeClient(conn).GetBlock(ctx, &runtime.GetBlockRequest{
+		RuntimeID: RUNTIME_ID,
+		Round:     roothash.RoundLatest,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("Nexus latest round:", nexusStatus.LatestBlock, "Node latest round:", latest.Header.Round,
+		"Behind:", int64(latest.Header.Round)-int64(nexusStatus.LatestBlock))
+	return nil
+}
+
+// the same round from the node and from nexus, checking that the hash and
+// transaction count agree
+func CompareNexus(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	client := runtime.NewRuntimeClient(conn)
+	start := time.Now()
+	block, err := client.GetBlock(ctx, &runtime.GetBlockRequest{
+		RuntimeID: RUNTIME_ID,
+		Round:     height,
+	})
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.times.GetBlock = time.Since(start)
+
+	start = time.Now()
+	txs, err := client.GetTransactionsWithResults(ctx, &runtime.GetTransactionsRequest{
+		RuntimeID: RUNTIME_ID,
+		Round:     height,
+	})
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.times.GetTransactions = time.Since(start)
+
+	var list nexusRuntimeBlockList
+	start = time.Now()
+	err = NexusGet(ctx, &list, fmt.Sprintf("/v1/%s/blocks?from=%d&to=%d&limit=1", NexusRuntime(), height, height))
+	status.times.NexusGetBlock = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	if len(list.Blocks) == 0 {
+		status.err = fmt.Errorf("round %d not indexed by nexus", height)
+		return status
+	}
+
+	nexusBlock := list.Blocks[0]
+	hash := block.Header.EncodedHash().Hex()
+	status.msg = fmt.Sprintf("Round: %d, gRPC: %s, Nexus: %s, NumTransactions: %d/%d, Hash: %s",
+		height, status.times.GetBlock+status.times.GetTransactions, status.times.NexusGetBlock, len(txs), nexusBlock.NumTransactions, hash)
+	switch {
+	case nexusBlock.Round != height:
+		status.err = fmt.Errorf("nexus returned round %d for %d", nexusBlock.Round, height)
+	case nexusBlock.Hash != hash:
+		status.err = fmt.Errorf("hash mismatch: node %s, nexus %s", hash, nexusBlock.Hash)
+	case nexusBlock.NumTransactions != len(txs):
+		status.err = fmt.Errorf("transaction count mismatch: node %d, nexus %d", len(txs), nexusBlock.NumTransactions)
+	}
+	return status
+}
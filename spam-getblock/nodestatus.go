@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	oasisGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+	control "github.com/oasisprotocol/oasis-core/go/control/api"
+)
+
+// what the node said about itself before the run, so results can be
+// interpreted later
+type NodeStatus struct {
+	Source             string // "control" or "consensus"
+	SoftwareVersion    string
+	ConsensusVersion   string
+	ConsensusStatus    string
+	LatestHeight       int64
+	LastRetainedHeight int64
+	LastRetainedRound  uint64 // of the runtime; only known from the control API
+}
+
+func (s *NodeStatus) String() string {
+	str := fmt.Sprintf("Source: %s, ConsensusVersion: %s, ConsensusStatus: %s, LatestHeight: %d, LastRetainedHeight: %d",
+		s.Source, s.ConsensusVersion, s.ConsensusStatus, s.LatestHeight, s.LastRetainedHeight)
+	if s.Source == "control" {
+		str = fmt.Sprintf("SoftwareVersion: %s, %s, LastRetainedRound: %d", s.SoftwareVersion, str, s.LastRetainedRound)
+	}
+	return str
+}
+
+// tries the control API first, which is normally only available on the
+// node's internal socket, and falls back to the public consensus status
+func GetNodeStatus(ctx context.Context) (*NodeStatus, error) {
+	conn, err := oasisGrpc.Dial(URL, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if status, err := control.NewNodeControllerClient(conn).GetStatus(ctx); err == nil {
+		ns := &NodeStatus{
+			Source:             "control",
+			SoftwareVersion:    status.SoftwareVersion,
+			ConsensusVersion:   status.Consensus.Version.String(),
+			ConsensusStatus:    status.Consensus.Status.String(),
+			LatestHeight:       status.Consensus.LatestHeight,
+			LastRetainedHeight: status.Consensus.LastRetainedHeight,
+		}
+		if rt, ok := status.Runtimes[RUNTIME_ID]; ok {
+			ns.LastRetainedRound = rt.LastRetainedRound
+		}
+		return ns, nil
+	}
+
+	status, err := consensus.NewConsensusClient(conn).GetStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeStatus{
+		Source:             "consensus",
+		ConsensusVersion:   status.Version.String(),
+		ConsensusStatus:    status.Status.String(),
+		LatestHeight:       status.LatestHeight,
+		LastRetainedHeight: status.LastRetainedHeight,
+	}, nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// OTLP/HTTP with the json encoding, which needs nothing beyond the stdlib
+
+const OTLP_SERVICE_NAME = "spam-getblock"
+
+// spans are batched and exported by a background goroutine
+const OTLP_BATCH_SIZE = 512
+
+// for every export, so an unreachable -otlp-endpoint can't hang the
+// exporters or the closes waiting on them at exit
+var otlpClient = &http.Client{Timeout: 10 * time.Second}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+func otlpString(key, value string) otlpAttribute {
+	return otlpAttribute{key, otlpValue{StringValue: &value}}
+}
+
+func otlpInt(key string, value int64) otlpAttribute {
+	s := strconv.FormatInt(value, 10)
+	return otlpAttribute{key, otlpValue{IntValue: &s}}
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"` // 1 ok, 2 error
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID      string          `json:"traceId"`
+	SpanID       string          `json:"spanId"`
+	ParentSpanID string          `json:"parentSpanId,omitempty"`
+	Name         string          `json:"name"`
+	Kind         int             `json:"kind"` // 3 client
+	Start        string          `json:"startTimeUnixNano"`
+	End          string          `json:"endTimeUnixNano"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+	Status       otlpStatus      `json:"status"`
+}
+
+func otlpResource() map[string]interface{} {
+	return map[string]interface{}{
+		"attributes": []otlpAttribute{otlpString("service.name", OTLP_SERVICE_NAME), otlpString("run.id", RUN_ID)},
+	}
+}
+
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func otlpID(n int) string {
+	b := make([]byte, n)
+	crand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// POSTs a json body to -otlp-endpoint + path
+func otlpPost(path string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	rsp, err := otlpClient.Post(strings.TrimSuffix(OTLP_ENDPOINT, "/")+path, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: http %s", path, rsp.Status)
+	}
+	return nil
+}
+
+// adds outgoing metadata to every call on the connection, e.g. a w3c
+// traceparent so the server's spans join the request's trace
+func metadataInterceptor(add func(context.Context) context.Context) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(add(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+func streamMetadataInterceptor(add func(context.Context) context.Context) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(add(ctx), desc, cc, method, opts...)
+	}
+}
+
+// gives the request a trace; false when tracing is off
+func StartTrace(status *ThreadStatus) bool {
+	if OTLP_ENDPOINT == "" || !OTLP_TRACES {
+		return false
+	}
+	status.traceID, status.spanID = otlpID(16), otlpID(8)
+	return true
+}
+
+// dial options that give the request a trace; nil when tracing is off
+func TraceDialOpts(status *ThreadStatus) []grpc.DialOption {
+	if !StartTrace(status) {
+		return nil
+	}
+	traceparent := func(ctx context.Context) context.Context {
+		return metadata.AppendToOutgoingContext(ctx, "traceparent", "00-"+status.traceID+"-"+status.spanID+"-01")
+	}
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(metadataInterceptor(traceparent)),
+		grpc.WithChainStreamInterceptor(streamMetadataInterceptor(traceparent)),
+	}
+}
+
+// the same for a shared connection, with the trace of whichever request
+// makes the call
+func SharedTraceDialOpts() []grpc.DialOption {
+	if OTLP_ENDPOINT == "" || !OTLP_TRACES {
+		return nil
+	}
+	traceparent := func(ctx context.Context) context.Context {
+		slot, ok := ctx.Value(statusSlotKey{}).(*statusSlot)
+		if !ok || slot.status == nil || slot.status.traceID == "" {
+			return ctx
+		}
+		return metadata.AppendToOutgoingContext(ctx, "traceparent", "00-"+slot.status.traceID+"-"+slot.status.spanID+"-01")
+	}
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(metadataInterceptor(traceparent)),
+		grpc.WithChainStreamInterceptor(streamMetadataInterceptor(traceparent)),
+	}
+}
+
+var (
+	otlpSpans   = make(chan []otlpSpan, 64)
+	otlpStopped = make(chan struct{})
+)
+
+// registers the span sink and starts the exporter
+func OpenTracing() {
+	statusSinks = append(statusSinks, TraceStatus)
+	go func() {
+		defer close(otlpStopped)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		var batch []otlpSpan
+		flush := func() {
+			if len(batch) == 0 {
+				return
+			}
+			err := otlpPost("/v1/traces", map[string]interface{}{
+				"resourceSpans": []interface{}{map[string]interface{}{
+					"resource": otlpResource(),
+					"scopeSpans": []interface{}{map[string]interface{}{
+						"scope": map[string]string{"name": OTLP_SERVICE_NAME},
+						"spans": batch,
+					}},
+				}},
+			})
+			if err != nil {
+				fmt.Println("OTLP error:", err)
+			}
+			batch = nil
+		}
+		for {
+			select {
+			case spans, ok := <-otlpSpans:
+				if !ok {
+					flush()
+					return
+				}
+				batch = append(batch, spans...)
+				if len(batch) >= OTLP_BATCH_SIZE {
+					flush()
+				}
+			case <-ticker.C:
+				flush()
+			}
+		}
+	}()
+}
+
+// exports what's left
+func CloseTracing() {
+	close(otlpSpans)
+	<-otlpStopped
+}
+
+// a span for the request with a child per phase; phases ran one after the
+// other from the start of Connect, so they're laid out end to end
+func TraceStatus(s *ThreadStatus) {
+	end := time.Now()
+	start := s.start
+	if start.IsZero() {
+		start = end.Add(-s.times.Total())
+		s.traceID, s.spanID = otlpID(16), otlpID(8)
+	}
+	root := otlpSpan{
+		TraceID:    s.traceID,
+		SpanID:     s.spanID,
+		Name:       CALL,
+		Kind:       3,
+		Start:      otlpTime(start),
+		End:        otlpTime(end),
+		Attributes: []otlpAttribute{otlpString("call", CALL), otlpInt("round", int64(s.ID)), otlpInt("bytes", s.bytes), otlpString("endpoint", s.endpoint)},
+		Status:     otlpStatus{Code: 1},
+	}
+	if s.err != nil {
+		root.Status = otlpStatus{Code: 2, Message: s.err.Error()}
+	}
+	spans := []otlpSpan{root}
+	t := start
+	child := func(name string, d time.Duration) {
+		spans = append(spans, otlpSpan{
+			TraceID:      s.traceID,
+			SpanID:       otlpID(8),
+			ParentSpanID: s.spanID,
+			Name:         name,
+			Kind:         3,
+			Start:        otlpTime(t),
+			End:          otlpTime(t.Add(d)),
+			Status:       otlpStatus{Code: 1},
+		})
+		t = t.Add(d)
+	}
+	child("Connect", s.times.Connect)
+	for _, phase := range s.times.Phases() {
+		if phase.Duration != 0 {
+			child(phase.Name, phase.Duration)
+		}
+	}
+	otlpSpans <- spans
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// explicit histogram bounds in ms for grpctest.latency
+var otlpLatencyBounds = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000, 30000, 60000}
+
+type otlpHistogram struct {
+	count   uint64
+	sum     float64
+	buckets []uint64 // len(otlpLatencyBounds)+1
+}
+
+func (h *otlpHistogram) add(v float64) {
+	i := sort.SearchFloat64s(otlpLatencyBounds, v)
+	h.count++
+	h.sum += v
+	h.buckets[i]++
+}
+
+// cumulative since the run started, guarded by otlpMetricsMutex
+var (
+	otlpMetricsMutex   sync.Mutex
+	otlpMetricsStart   time.Time
+	otlpRequests       uint64
+	otlpErrorsByCode   = map[string]uint64{}
+	otlpLatencyByPhase = map[string]*otlpHistogram{}
+	otlpMetricsStop    = make(chan struct{})
+	otlpMetricsStopped = make(chan struct{})
+)
+
+// registers the metrics sink and exports every -otlp-metrics-interval
+func OpenOtlpMetrics() {
+	otlpMetricsStart = time.Now()
+	statusSinks = append(statusSinks, func(s *ThreadStatus) {
+		otlpMetricsMutex.Lock()
+		defer otlpMetricsMutex.Unlock()
+		otlpRequests++
+		if s.err != nil {
+			otlpErrorsByCode[errorCode(s.err)]++
+		}
+		observe := func(phase string, d time.Duration) {
+			h, ok := otlpLatencyByPhase[phase]
+			if !ok {
+				h = &otlpHistogram{buckets: make([]uint64, len(otlpLatencyBounds)+1)}
+				otlpLatencyByPhase[phase] = h
+			}
+			h.add(ms(d))
+		}
+		observe("Connect", s.times.Connect)
+		for _, phase := range s.times.Phases() {
+			if phase.Duration != 0 {
+				observe(phase.Name, phase.Duration)
+			}
+		}
+		observe("total", s.times.Total())
+	})
+	go func() {
+		defer close(otlpMetricsStopped)
+		ticker := time.NewTicker(OTLP_METRICS_INTERVAL)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ExportOtlpMetrics()
+			case <-otlpMetricsStop:
+				ExportOtlpMetrics()
+				return
+			}
+		}
+	}()
+}
+
+// exports the final values
+func CloseOtlpMetrics() {
+	close(otlpMetricsStop)
+	<-otlpMetricsStopped
+}
+
+// builds the export from copies of the cumulative values under the lock
+// and posts it after, so a slow collector doesn't hold up the goroutine
+// collecting statuses
+func ExportOtlpMetrics() {
+	otlpMetricsMutex.Lock()
+
+	start, now := otlpTime(otlpMetricsStart), otlpTime(time.Now())
+	call := otlpString("call", CALL)
+	point := func(value uint64, attributes ...otlpAttribute) map[string]interface{} {
+		return map[string]interface{}{
+			"attributes":        append([]otlpAttribute{call}, attributes...),
+			"startTimeUnixNano": start,
+			"timeUnixNano":      now,
+			"asInt":             strconv.FormatUint(value, 10),
+		}
+	}
+	sum := func(name string, points []map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"name": name,
+			"unit": "1",
+			"sum": map[string]interface{}{
+				"aggregationTemporality": 2, // cumulative
+				"isMonotonic":            true,
+				"dataPoints":             points,
+			},
+		}
+	}
+
+	var errorPoints []map[string]interface{}
+	for code, n := range otlpErrorsByCode {
+		errorPoints = append(errorPoints, point(n, otlpString("code", code)))
+	}
+	var latencyPoints []map[string]interface{}
+	for phase, h := range otlpLatencyByPhase {
+		buckets := make([]string, len(h.buckets))
+		for i, c := range h.buckets {
+			buckets[i] = strconv.FormatUint(c, 10)
+		}
+		latencyPoints = append(latencyPoints, map[string]interface{}{
+			"attributes":        []otlpAttribute{call, otlpString("phase", phase)},
+			"startTimeUnixNano": start,
+			"timeUnixNano":      now,
+			"count":             strconv.FormatUint(h.count, 10),
+			"sum":               h.sum,
+			"bucketCounts":      buckets,
+			"explicitBounds":    otlpLatencyBounds,
+		})
+	}
+	metrics := []interface{}{
+		sum("grpctest.requests", []map[string]interface{}{point(otlpRequests)}),
+		sum("grpctest.errors", errorPoints),
+		map[string]interface{}{
+			"name": "grpctest.latency",
+			"unit": "ms",
+			"histogram": map[string]interface{}{
+				"aggregationTemporality": 2,
+				"dataPoints":             latencyPoints,
+			},
+		},
+	}
+	otlpMetricsMutex.Unlock()
+
+	err := otlpPost("/v1/metrics", map[string]interface{}{
+		"resourceMetrics": []interface{}{map[string]interface{}{
+			"resource": otlpResource(),
+			"scopeMetrics": []interface{}{map[string]interface{}{
+				"scope":   map[string]string{"name": OTLP_SERVICE_NAME},
+				"metrics": metrics,
+			}},
+		}},
+	})
+	if err != nil {
+		fmt.Println("OTLP error:", err)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// -pin-cert, parsed; several let a monitor ride through a certificate
+// rotation
+var pinnedCerts [][]byte
+
+func CheckPinCert() error {
+	if PIN_CERT == "" {
+		return nil
+	}
+	if PLAINTEXT {
+		return fmt.Errorf("-pin-cert needs TLS, not -plaintext")
+	}
+	for _, s := range strings.Split(PIN_CERT, ",") {
+		// as openssl x509 -fingerprint -sha256 prints it, or plain hex
+		pin, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(s), ":", ""))
+		if err != nil || len(pin) != sha256.Size {
+			return fmt.Errorf("bad -pin-cert '%s', expected a sha256 fingerprint in hex", s)
+		}
+		pinnedCerts = append(pinnedCerts, pin)
+	}
+	return nil
+}
+
+// runs after the usual chain verification, or instead of it with
+// -insecure-skip-verify, which makes pinning the way to trust a self-signed
+// certificate; a mismatch fails the handshake, so it's counted as
+// TLSHandshake
+func verifyPinnedCert(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("no certificate to check against -pin-cert")
+	}
+	sum := sha256.Sum256(rawCerts[0])
+	for _, pin := range pinnedCerts {
+		if bytes.Equal(pin, sum[:]) {
+			return nil
+		}
+	}
+	return fmt.Errorf("certificate sha256 %x doesn't match -pin-cert", sum)
+}
+
+// for tls.Config.VerifyPeerCertificate, nil without -pin-cert
+func pinVerifier() func([][]byte, [][]*x509.Certificate) error {
+	if len(pinnedCerts) == 0 {
+		return nil
+	}
+	return verifyPinnedCert
+}
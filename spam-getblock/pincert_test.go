@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCheckPinCert(t *testing.T) {
+	defer func(pin string, plaintext bool) {
+		PIN_CERT, PLAINTEXT, pinnedCerts = pin, plaintext, nil
+	}(PIN_CERT, PLAINTEXT)
+
+	sum := sha256.Sum256([]byte("certificate"))
+	plain := fmt.Sprintf("%x", sum)
+	var colons []string
+	for _, b := range sum {
+		colons = append(colons, fmt.Sprintf("%02X", b))
+	}
+	openssl := strings.Join(colons, ":")
+
+	for _, tt := range []struct {
+		name      string
+		pin       string
+		plaintext bool
+		ok        bool
+		want      int
+	}{
+		{name: "unset", ok: true},
+		{name: "hex", pin: plain, ok: true, want: 1},
+		{name: "openssl", pin: openssl, ok: true, want: 1},
+		{name: "rotation", pin: plain + ", " + openssl, ok: true, want: 2},
+		{name: "short", pin: plain[:62]},
+		{name: "sha1", pin: plain[:40]},
+		{name: "not hex", pin: strings.Repeat("zz", 32)},
+		{name: "empty entry", pin: plain + ","},
+		{name: "plaintext", pin: plain, plaintext: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			PIN_CERT, PLAINTEXT, pinnedCerts = tt.pin, tt.plaintext, nil
+			err := CheckPinCert()
+			if (err == nil) != tt.ok {
+				t.Fatalf("error %v", err)
+			}
+			if tt.ok && len(pinnedCerts) != tt.want {
+				t.Fatalf("%d pins, expected %d", len(pinnedCerts), tt.want)
+			}
+			if (pinVerifier() == nil) != (len(pinnedCerts) == 0) {
+				t.Fatalf("verifier set with %d pins", len(pinnedCerts))
+			}
+		})
+	}
+}
+
+func TestVerifyPinnedCert(t *testing.T) {
+	defer func(pins [][]byte) { pinnedCerts = pins }(pinnedCerts)
+	leaf, other := []byte("leaf"), []byte("other")
+	leafSum, otherSum := sha256.Sum256(leaf), sha256.Sum256(other)
+
+	for _, tt := range []struct {
+		name  string
+		pins  [][]byte
+		chain [][]byte
+		ok    bool
+	}{
+		{"match", [][]byte{leafSum[:]}, [][]byte{leaf, other}, true},
+		{"second pin", [][]byte{otherSum[:], leafSum[:]}, [][]byte{leaf}, true},
+		{"mismatch", [][]byte{otherSum[:]}, [][]byte{leaf}, false},
+		{"only the leaf counts", [][]byte{otherSum[:]}, [][]byte{leaf, other}, false},
+		{"no certificate", [][]byte{leafSum[:]}, nil, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			pinnedCerts = tt.pins
+			if err := verifyPinnedCert(tt.chain, nil); (err == nil) != tt.ok {
+				t.Fatalf("error %v", err)
+			}
+		})
+	}
+}
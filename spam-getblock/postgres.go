@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// rows per COPY into grpctest_requests
+const POSTGRES_BATCH = 1000
+
+// applied in order; the number applied so far is kept in
+// grpctest_schema_version so every load host can migrate a shared database
+var postgresMigrations = []string{
+	`CREATE TABLE grpctest_runs (
+		run_id   TEXT PRIMARY KEY,
+		host     TEXT NOT NULL,
+		started  TIMESTAMPTZ NOT NULL,
+		call     TEXT NOT NULL,
+		endpoint TEXT NOT NULL,
+		runtime  TEXT NOT NULL,
+		seed     BIGINT NOT NULL
+	);
+	CREATE TABLE grpctest_requests (
+		run_id     TEXT NOT NULL REFERENCES grpctest_runs(run_id),
+		finished   TIMESTAMPTZ NOT NULL,
+		round      BIGINT NOT NULL,
+		total_ms   DOUBLE PRECISION NOT NULL,
+		connect_ms DOUBLE PRECISION NOT NULL,
+		bytes      BIGINT NOT NULL,
+		code       TEXT NOT NULL,
+		error      TEXT
+	);
+	CREATE INDEX grpctest_requests_run_id ON grpctest_requests(run_id);`,
+}
+
+var (
+	postgresConn *pgx.Conn
+	postgresRows [][]any
+)
+
+func migratePostgres(ctx context.Context, conn *pgx.Conn) error {
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS grpctest_schema_version (version INT NOT NULL)`); err != nil {
+		return err
+	}
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	// serializes hosts starting at the same time against a fresh database
+	if _, err := tx.Exec(ctx, `LOCK TABLE grpctest_schema_version`); err != nil {
+		return err
+	}
+	version := 0
+	err = tx.QueryRow(ctx, `SELECT version FROM grpctest_schema_version`).Scan(&version)
+	if err == pgx.ErrNoRows {
+		_, err = tx.Exec(ctx, `INSERT INTO grpctest_schema_version VALUES (0)`)
+	}
+	if err != nil {
+		return err
+	}
+	for ; version < len(postgresMigrations); version++ {
+		if _, err := tx.Exec(ctx, postgresMigrations[version]); err != nil {
+			return fmt.Errorf("migration %d: %w", version+1, err)
+		}
+	}
+	if _, err := tx.Exec(ctx, `UPDATE grpctest_schema_version SET version = $1`, version); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// connects to -postgres, migrates the schema and adds this run; requests
+// are copied in batches as they finish
+func OpenPostgres() error {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, POSTGRES)
+	if err != nil {
+		return err
+	}
+	if err := migratePostgres(ctx, conn); err != nil {
+		conn.Close(ctx)
+		return err
+	}
+	host, _ := os.Hostname()
+	_, err = conn.Exec(ctx, `INSERT INTO grpctest_runs (run_id, host, started, call, endpoint, runtime, seed) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		RUN_ID, host, time.Now(), CALL, URL, RUNTIME, SEED)
+	if err != nil {
+		conn.Close(ctx)
+		return fmt.Errorf("run %s: %w", RUN_ID, err)
+	}
+	postgresConn = conn
+	statusSinks = append(statusSinks, WritePostgresRow)
+	return nil
+}
+
+func WritePostgresRow(s *ThreadStatus) {
+	var errStr *string
+	if s.err != nil {
+		e := s.err.Error()
+		errStr = &e
+	}
+	postgresRows = append(postgresRows, []any{RUN_ID, time.Now(), int64(s.ID),
+		ms(s.times.Total()), ms(s.times.Connect), s.bytes, errorCode(s.err), errStr})
+	if len(postgresRows) >= POSTGRES_BATCH {
+		FlushPostgres()
+	}
+}
+
+func FlushPostgres() {
+	if len(postgresRows) == 0 {
+		return
+	}
+	_, err := postgresConn.CopyFrom(context.Background(), pgx.Identifier{"grpctest_requests"},
+		[]string{"run_id", "finished", "round", "total_ms", "connect_ms", "bytes", "code", "error"},
+		pgx.CopyFromRows(postgresRows))
+	if err != nil {
+		fmt.Println("Postgres error:", err)
+	}
+	postgresRows = postgresRows[:0]
+}
+
+// copies the last batch
+func ClosePostgres() {
+	if postgresConn == nil {
+		return
+	}
+	FlushPostgres()
+	postgresConn.Close(context.Background())
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+var progress struct {
+	start   time.Time
+	done    int64
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// redraws "done/total rate ETA" in place on stderr until StopProgress, so
+// stdout stays clean for -output json and per request lines
+func StartProgress() {
+	progress.start = time.Now()
+	progress.stop = make(chan struct{})
+	progress.stopped = make(chan struct{})
+	statusSinks = append(statusSinks, func(*ThreadStatus) {
+		atomic.AddInt64(&progress.done, 1)
+	})
+	go func() {
+		defer close(progress.stopped)
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				drawProgress()
+			case <-progress.stop:
+				drawProgress()
+				fmt.Fprintln(os.Stderr)
+				return
+			}
+		}
+	}()
+}
+
+func StopProgress() {
+	close(progress.stop)
+	<-progress.stopped
+}
+
+func drawProgress() {
+	const width = 30
+	done := atomic.LoadInt64(&progress.done)
+	total := int64(NUM_REQUESTS)
+	if total <= 0 {
+		return
+	}
+	elapsed := time.Since(progress.start)
+	rate := float64(done) / elapsed.Seconds()
+	eta := "?"
+	if rate > 0 {
+		remaining := time.Duration(float64(total-done) / rate * float64(time.Second))
+		eta = remaining.Round(time.Second).String()
+	}
+	filled := int(width * done / total)
+	bar := make([]byte, width)
+	for i := range bar {
+		if i < filled {
+			bar[i] = '#'
+		} else {
+			bar[i] = '.'
+		}
+	}
+	// trailing spaces clear what's left of a longer previous line
+	fmt.Fprintf(os.Stderr, "\r[%s] %d/%d %.1f/s ETA %s   ", bar, done, total, rate, eta)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+	"google.golang.org/grpc"
+)
+
+// parsed -proxy; nil means HTTPS_PROXY and NO_PROXY decide per target
+var proxyURL *url.URL
+
+func CheckProxy() error {
+	if PROXY == "" {
+		return nil
+	}
+	u, err := url.Parse(PROXY)
+	if err != nil {
+		return fmt.Errorf("bad -proxy: %w", err)
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h", "http":
+	default:
+		return fmt.Errorf("bad -proxy '%s', expected socks5://host:port or http://host:port", PROXY)
+	}
+	proxyURL = u
+	return nil
+}
+
+// the proxy to reach addr through, or nil to dial it directly
+func proxyFor(addr string) (*url.URL, error) {
+	if proxyURL != nil {
+		return proxyURL, nil
+	}
+	return http.ProxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: "https", Host: addr}})
+}
+
+// dials addr through its proxy; the proxy resolves the name, so this works
+// without DNS for the target
+func dialProxy(ctx context.Context, p *url.URL, addr string) (net.Conn, error) {
+	if p.Scheme != "http" {
+		d, err := proxy.FromURL(p, proxy.Direct)
+		if err != nil {
+			return nil, err
+		}
+		return d.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", p.Host)
+	if err != nil {
+		return nil, err
+	}
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Host: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if p.User != nil {
+		password, _ := p.User.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(p.User.Username()+":"+password)))
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	rsp, err := http.ReadResponse(r, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: CONNECT %s: %s", p.Host, addr, rsp.Status)
+	}
+	if r.Buffered() > 0 {
+		return &bufferedConn{conn, r}, nil
+	}
+	return conn, nil
+}
+
+// a connection whose first bytes were read into r along with the CONNECT
+// response
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// dials addr through its proxy if it has one, else directly in -ip-family,
+// either within -dial-timeout
+func dialTarget(parent context.Context, addr string) (net.Conn, error) {
+	p, err := proxyFor(addr)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := withDialTimeout(parent)
+	defer cancel()
+	var conn net.Conn
+	if p != nil {
+		conn, err = dialProxy(ctx, p, addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, ipNetwork("tcp"), addr)
+	}
+	if err == nil {
+		conn = trackTcp(conn)
+	}
+	return conn, dialTimeout(parent, ctx, "connect", err)
+}
+
+// -proxy, -ip-family, -dial-timeout and -tcp-info as a dial option; grpc
+// honors HTTPS_PROXY by itself, but only without a custom dialer, which is
+// why dialTarget and byteCounter.dial check proxyFor too
+func netDialOpts() []grpc.DialOption {
+	if proxyURL == nil && IP_FAMILY == "auto" && DIAL_TIMEOUT <= 0 && !TCP_INFO {
+		return nil
+	}
+	return []grpc.DialOption{grpc.WithContextDialer(dialTarget)}
+}
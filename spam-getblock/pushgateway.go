@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// pushes the run's final aggregates to -pushgateway under -job, grouped by
+// endpoint, call and run id so runs don't overwrite each other
+func PushSummary(s *Summary) error {
+	reg := prometheus.NewRegistry()
+	gauge := func(name, help string, value float64) {
+		g := prometheus.NewGauge(prometheus.GaugeOpts{Name: name, Help: help})
+		g.Set(value)
+		reg.MustRegister(g)
+	}
+	gauge("grpctest_run_requests", "Requests in the run.", float64(s.Requests))
+	gauge("grpctest_run_errors", "Failed requests in the run.", float64(s.Errors))
+	gauge("grpctest_run_seconds", "Wall time of the run.", s.TotalSeconds)
+	gauge("grpctest_run_rate", "Requests per second.", s.Rate)
+
+	errors := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "grpctest_run_errors_by_code",
+		Help: "Failed requests by grpc status code.",
+	}, []string{"code"})
+	for code, n := range s.ErrorKinds {
+		errors.WithLabelValues(code).Set(float64(n))
+	}
+	reg.MustRegister(errors)
+
+	latency := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "grpctest_run_latency_seconds",
+		Help: "Latency percentiles of each phase, and of whole requests as phase total.",
+	}, []string{"phase", "quantile"})
+	set := func(phase string, p PercentileSummary) {
+		latency.WithLabelValues(phase, "0.5").Set(p.P50 / 1000)
+		latency.WithLabelValues(phase, "0.9").Set(p.P90 / 1000)
+		latency.WithLabelValues(phase, "0.99").Set(p.P99 / 1000)
+		latency.WithLabelValues(phase, "0.999").Set(p.P999 / 1000)
+		latency.WithLabelValues(phase, "1").Set(p.Max / 1000)
+	}
+	for phase, p := range s.Phases {
+		set(phase, p)
+	}
+	set("total", s.Total)
+	reg.MustRegister(latency)
+
+	return push.New(PUSHGATEWAY, JOB).
+		Gatherer(reg).
+		Grouping("endpoint", URL).
+		Grouping("call", CALL).
+		Grouping("run_id", RUN_ID).
+		Push()
+}
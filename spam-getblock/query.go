@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/oasisprotocol/oasis-sdk/client-sdk/go/client"
+	"github.com/oasisprotocol/oasis-sdk/client-sdk/go/modules/accounts"
+	"github.com/oasisprotocol/oasis-sdk/client-sdk/go/modules/core"
+)
+
+// runtime.Query calls; these go through the runtime's query dispatcher
+// instead of only reading block storage
+
+func QueryCoreParameters(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	rc := client.New(conn, RUNTIME_ID)
+	start := time.Now()
+	params, err := core.NewV1(rc).Parameters(ctx, height)
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("Round: %d, MaxBatchGas: %d, MaxTxSize: %d", height, params.MaxBatchGas, params.MaxTxSize)
+	return status
+}
+
+func QueryAccountsBalances(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	address := PickAccountAddress()
+	rc := client.New(conn, RUNTIME_ID)
+	start := time.Now()
+	balances, err := accounts.NewV1(rc).Balances(ctx, height, address)
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("Round: %d, Address: %s, Balances: %v", height, address, balances.Balances)
+	return status
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+)
+
+// set once by LoadRawPayload
+var rawPayload cbor.RawMessage
+
+// reads -payload and checks it is a single well formed cbor item
+func LoadRawPayload(ctx context.Context) error {
+	if METHOD == "" {
+		return fmt.Errorf("raw needs -method, e.g. oasis-core.RuntimeClient/GetBlock")
+	}
+	if PAYLOAD == "" {
+		// methods without arguments take a cbor null
+		rawPayload = cbor.Marshal(nil)
+		return nil
+	}
+	data, err := os.ReadFile(PAYLOAD)
+	if err != nil {
+		return err
+	}
+	var v interface{}
+	if err := cbor.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("bad -payload: %w", err)
+	}
+	rawPayload = data
+	return nil
+}
+
+// full grpc method name for -method, with or without the leading slash
+func RawMethod() string {
+	return "/" + strings.TrimPrefix(METHOD, "/")
+}
+
+// invokes -method with the -payload body as is; the response is kept as
+// raw cbor since its type is unknown
+func InvokeRaw(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	var rsp cbor.RawMessage
+	start := time.Now()
+	err = conn.Invoke(ctx, RawMethod(), rawPayload, &rsp)
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("Method: %s, ResponseSize: %d", RawMethod(), len(rsp))
+	return status
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// a shared connection's outage windows, once it has been READY
+type connWindow struct {
+	ready         bool      // has been READY at least once
+	downSince     time.Time // zero while READY
+	failedSince   time.Time // zero unless in TRANSIENT_FAILURE
+	reconnectedAt time.Time // last READY after an outage
+}
+
+// what grpc's automatic reconnects of shared connections cost
+type Reconnects struct {
+	Reconnections    int     `json:"reconnections"`
+	TransientFailure float64 `json:"transient_failure_ms"` // summed over connections
+	FailedWhileDown  int     `json:"failed_while_down"`    // requests that overlapped an outage
+	Errors           int     `json:"errors"`               // all failed requests on shared connections
+}
+
+var (
+	reconnectMutex sync.Mutex
+	connWindows    = map[string]*connWindow{}
+	reconnects     Reconnects
+)
+
+// follows a pool connection's state to count its reconnects and outages
+func WatchReconnects(conn *grpc.ClientConn, label string) {
+	state := conn.GetState()
+	reconnectMutex.Lock()
+	w := &connWindow{}
+	connWindows[label] = w
+	reconnectMutex.Unlock()
+	go func() {
+		for state != connectivity.Shutdown && conn.WaitForStateChange(context.Background(), state) {
+			state = conn.GetState()
+			now := time.Now()
+			reconnectMutex.Lock()
+			if !w.failedSince.IsZero() && state != connectivity.TransientFailure {
+				reconnects.TransientFailure += ms(now.Sub(w.failedSince))
+				w.failedSince = time.Time{}
+			}
+			switch state {
+			case connectivity.Ready:
+				if !w.downSince.IsZero() {
+					reconnects.Reconnections++
+					w.reconnectedAt = now
+				}
+				w.ready, w.downSince = true, time.Time{}
+			case connectivity.TransientFailure:
+				if w.failedSince.IsZero() {
+					w.failedSince = now
+				}
+				fallthrough
+			case connectivity.Idle, connectivity.Connecting:
+				if w.ready && w.downSince.IsZero() {
+					w.downSince = now
+				}
+			}
+			reconnectMutex.Unlock()
+		}
+	}()
+}
+
+// a failed request counts against the reconnect if its connection was
+// down at some point between its start and end
+func RecordReconnect(s *ThreadStatus) {
+	if s.err == nil || s.poolConn == "" {
+		return
+	}
+	reconnectMutex.Lock()
+	defer reconnectMutex.Unlock()
+	reconnects.Errors++
+	w, ok := connWindows[s.poolConn]
+	if !ok {
+		return
+	}
+	end := s.start.Add(s.times.Total())
+	if (!w.downSince.IsZero() && w.downSince.Before(end)) || w.reconnectedAt.After(s.start) {
+		reconnects.FailedWhileDown++
+	}
+}
+
+// with outages still going counted up to now
+func ReconnectSummary() *Reconnects {
+	if CONN_MODE == "per-request" {
+		return nil
+	}
+	reconnectMutex.Lock()
+	defer reconnectMutex.Unlock()
+	r := reconnects
+	for _, w := range connWindows {
+		if !w.failedSince.IsZero() {
+			r.TransientFailure += ms(time.Since(w.failedSince))
+		}
+	}
+	return &r
+}
+
+// requests lost to reconnects apart from the server's own errors
+func PrintReconnects() {
+	r := ReconnectSummary()
+	if r == nil || (r.Reconnections == 0 && r.TransientFailure == 0 && r.FailedWhileDown == 0) {
+		return
+	}
+	fmt.Printf("Reconnections: %d, in TRANSIENT_FAILURE: %s, failed while reconnecting: %d / %d errors\n",
+		r.Reconnections, time.Duration(r.TransientFailure*float64(time.Millisecond)).Round(time.Millisecond), r.FailedWhileDown, r.Errors)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// password in a key=value postgres dsn, or in a url query
+var dsnPassword = regexp.MustCompile(`password\s*=\s*('(?:[^'\\]|\\.)*'|[^\s&]*)`)
+
+// the value of a flag as it goes into the summaries and reports: -header
+// keeps its keys but not their values, which are often tokens, and -postgres
+// and -proxy lose their user and password
+func redactedFlag(f *flag.Flag) string {
+	switch f.Name {
+	case "header":
+		var s []string
+		for i := 0; i < len(HEADERS.pairs); i += 2 {
+			s = append(s, HEADERS.pairs[i]+"=***")
+		}
+		return strings.Join(s, ",")
+	case "postgres", "proxy":
+		return redactCredentials(f.Value.String())
+	}
+	return f.Value.String()
+}
+
+func redactCredentials(s string) string {
+	if u, err := url.Parse(s); err == nil && u.User != nil {
+		u.User = nil
+		s = u.String()
+	}
+	return dsnPassword.ReplaceAllString(s, "password=***")
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+// the large registry reads explorers issue
+
+func GetRegistryNodes(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	start := time.Now()
+	nodes, err := registry.NewRegistryClient(conn).GetNodes(ctx, int64(height))
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("Height: %d, NumNodes: %d", height, len(nodes))
+	return status
+}
+
+func GetRegistryEntities(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	start := time.Now()
+	entities, err := registry.NewRegistryClient(conn).GetEntities(ctx, int64(height))
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("Height: %d, NumEntities: %d", height, len(entities))
+	return status
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"time"
+)
+
+type PercentileSummary struct {
+	Count uint64  `json:"count"`
+	Min   float64 `json:"min_ms"`
+	Mean  float64 `json:"mean_ms"`
+	P50   float64 `json:"p50_ms"`
+	P90   float64 `json:"p90_ms"`
+	P95   float64 `json:"p95_ms"`
+	P99   float64 `json:"p99_ms"`
+	P999  float64 `json:"p99_9_ms"`
+	Max   float64 `json:"max_ms"`
+	Share float64 `json:"share,omitempty"` // of total latency, for phases
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func (h *Histogram) Summary() PercentileSummary {
+	return PercentileSummary{
+		Count: h.Count,
+		Min:   ms(h.Min),
+		Mean:  ms(h.Mean()),
+		P50:   ms(h.Percentile(0.5)),
+		P90:   ms(h.Percentile(0.9)),
+		P95:   ms(h.Percentile(0.95)),
+		P99:   ms(h.Percentile(0.99)),
+		P999:  ms(h.Percentile(0.999)),
+		Max:   ms(h.Max),
+	}
+}
+
+// everything a dashboard needs from one run
+type Summary struct {
+	Config             map[string]string             `json:"config"` // every flag, set or default
+	Seed               int64                         `json:"seed"`
+	ApiVersion         string                        `json:"api_version"`
+	ConnMode           string                        `json:"conn_mode"`
+	InsecureSkipVerify bool                          `json:"insecure_skip_verify"` // server certificates weren't checked
+	Requests           int                           `json:"requests"`
+	Errors             int                           `json:"errors"`
+	ErrorKinds         map[string]int                `json:"error_kinds"`
+	ErrorClasses       []*ErrorClass                 `json:"error_classes"`
+	Retries            *Retries                      `json:"retries,omitempty"` // with -retry-max-attempts
+	Hedging            *Hedging                      `json:"hedging,omitempty"` // with -hedge-after
+	KnownBadRequests   int                           `json:"known_bad_requests"`
+	KnownBadErrors     int                           `json:"known_bad_errors"`
+	TotalSeconds       float64                       `json:"total_seconds"`
+	Rate               float64                       `json:"rate"`
+	Phases             map[string]PercentileSummary  `json:"phases"`
+	Total              PercentileSummary             `json:"total"`
+	Queued             PercentileSummary             `json:"queued"`     // waiting for a ready connection
+	NotQueued          PercentileSummary             `json:"not_queued"` // total less the above
+	Bandwidth          map[string]BandwidthSummary   `json:"bandwidth"`
+	Series             []*SeriesPoint                `json:"series"`
+	Endpoints          map[string]EndpointSummary    `json:"endpoints,omitempty"` // with several -url
+	PoolConns          map[string]EndpointSummary    `json:"pool_connections,omitempty"`
+	Backends           map[string]EndpointSummary    `json:"backends,omitempty"`    // by address, with -lb-policy
+	IpFamilies         map[string]EndpointSummary    `json:"ip_families,omitempty"` // IPv4, IPv6
+	Apdex              map[string]*Apdex             `json:"apdex,omitempty"`       // by call, with -apdex-threshold
+	Connection         map[string]PercentileSummary  `json:"connection"`            // setup of fresh connections
+	Disconnects        Disconnects                   `json:"disconnects"`
+	Connectivity       *StateSummary                 `json:"connectivity"`          // state changes of every connection
+	Reconnects         *Reconnects                   `json:"reconnects,omitempty"`  // of shared and pool connections
+	TcpInfo            *TcpInfoSummary               `json:"tcp_info,omitempty"`    // with -tcp-info
+	Tls                []TlsParamsSummary            `json:"tls,omitempty"`         // handshakes by negotiated version and cipher
+	Compression        map[string]CompressionSummary `json:"compression,omitempty"` // by compressor, with -compress
+	Slowest            []SlowRequest                 `json:"slowest"`
+	Metadata           map[string]map[string]int     `json:"metadata"` // response header values by key
+	Assertions         []Assertion                   `json:"assertions,omitempty"`
+	Passed             bool                          `json:"passed"` // every assertion held
+	Node               *NodeStatus                   `json:"node,omitempty"`
+	Client             *ClientUsage                  `json:"client"`
+}
+
+func NewSummary(num_requests, num_errors int, time_taken time.Duration, nodeStatus *NodeStatus, client *ClientUsage) *Summary {
+	s := &Summary{
+		Config:             map[string]string{},
+		Seed:               SEED,
+		ApiVersion:         API_VERSION,
+		ConnMode:           CONN_MODE,
+		InsecureSkipVerify: INSECURE_SKIP_VERIFY,
+		Requests:           num_requests,
+		Errors:             num_errors,
+		ErrorKinds:         errorKinds,
+		ErrorClasses:       ErrorClasses(),
+		Retries:            RetrySummary(),
+		Hedging:            HedgeSummary(),
+		KnownBadRequests:   knownBadHits,
+		KnownBadErrors:     knownBadErrors,
+		TotalSeconds:       time_taken.Seconds(),
+		Rate:               float64(num_requests) / time_taken.Seconds(),
+		Phases:             map[string]PercentileSummary{},
+		Total:              latencies.Total.Summary(),
+		Queued:             queued.Summary(),
+		NotQueued:          notQueued.Summary(),
+		Bandwidth:          Bandwidth(),
+		Series:             Series(),
+		Endpoints:          EndpointSummaries(time_taken),
+		PoolConns:          PoolConnSummaries(time_taken),
+		Backends:           BackendSummaries(time_taken),
+		IpFamilies:         IpFamilySummaries(time_taken),
+		Apdex:              apdex,
+		Connection:         map[string]PercentileSummary{},
+		Disconnects:        disconnects,
+		Connectivity:       StateTransitions(),
+		Reconnects:         ReconnectSummary(),
+		TcpInfo:            TcpInfo(),
+		Tls:                TlsParams(),
+		Compression:        Compression(),
+		Slowest:            slowest,
+		Metadata:           metadataValues,
+		Assertions:         CheckAssertions(num_requests, num_errors, time_taken),
+		Node:               nodeStatus,
+		Client:             client,
+	}
+	for name, h := range connLatencies.Phases {
+		s.Connection[name] = h.Summary()
+	}
+	if connLatencies.Total.Count != 0 {
+		s.Connection["Ready"] = connLatencies.Total.Summary()
+	}
+	s.Passed = !AssertionsFailed(s.Assertions)
+	flag.VisitAll(func(f *flag.Flag) { s.Config[f.Name] = redactedFlag(f) })
+	for name, h := range latencies.Phases {
+		p := h.Summary()
+		p.Share = latencies.Share(name)
+		s.Phases[name] = p
+	}
+	return s
+}
+
+// stdout as it was at startup; SeparateReportOutput may point os.Stdout
+// elsewhere afterwards
+var reportOut = os.Stdout
+
+// with -output json or markdown going to stdout, the text output moves to
+// stderr so the summary can be piped on its own
+func SeparateReportOutput() {
+	if OUTPUT != "text" && (OUT == "" || OUT == "-") {
+		os.Stdout = os.Stderr
+	}
+}
+
+// writes the summary as indented json to -out, or stdout if it's empty or -
+func (s *Summary) Write() error {
+	f := reportOut
+	if OUT != "" && OUT != "-" {
+		var err error
+		if f, err = os.Create(OUT); err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+)
+
+// backend address -> the -url it was resolved from, with -resolve-all
+var resolvedFrom = map[string]string{}
+
+// replaces every -url with one endpoint per A/AAAA record of its host,
+// each keeping the url's weight, so every backend behind a round robin DNS
+// name gets its own connections and its own line in the report
+func ResolveAll(ctx context.Context) error {
+	var urls []string
+	var weights []int
+	for i, u := range URLS {
+		host, port, err := net.SplitHostPort(u)
+		if err != nil {
+			return fmt.Errorf("-resolve-all needs host:port urls: %w", err)
+		}
+		ips, err := net.DefaultResolver.LookupIP(ctx, ipNetwork("ip"), host)
+		if err != nil {
+			return err
+		}
+		for _, ip := range ips {
+			backend := net.JoinHostPort(ip.String(), port)
+			resolvedFrom[backend] = u
+			urls = append(urls, backend)
+			weights = append(weights, URL_WEIGHTS[i])
+		}
+	}
+	if len(urls) == 0 {
+		return fmt.Errorf("no addresses for %s", URLS)
+	}
+	URLS, URL_WEIGHTS = urls, weights
+	return nil
+}
+
+// per endpoint dial options: a resolved backend is dialed by address but
+// keeps its url's host as the authority, which is also the TLS server name
+func endpointDialOpts(endpoint string) []grpc.DialOption {
+	if u, ok := resolvedFrom[endpoint]; ok {
+		return []grpc.DialOption{grpc.WithAuthority(u)}
+	}
+	return nil
+}
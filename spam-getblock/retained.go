@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	oasisGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+	roothash "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	runtime "github.com/oasisprotocol/oasis-core/go/runtime/client/api"
+)
+
+// narrows [min, max) to [first, last], leaving it alone if they don't overlap
+func clamp(name string, min, max *uint64, first, last uint64) {
+	if *min == 0 && *max == math.MaxUint64 {
+		// not set by flags; sample whatever the node has
+		*min, *max = first, last+1
+		fmt.Printf("Using %s range %d-%d from the node\n", name, *min, *max)
+		return
+	}
+	newMin, newMax := *min, *max
+	if first > newMin {
+		newMin = first
+	}
+	if last+1 < newMax {
+		newMax = last + 1
+	}
+	if newMin >= newMax {
+		fmt.Printf("Warning: %s range %d-%d is outside the retained window %d-%d\n", name, *min, *max, first, last)
+		return
+	}
+	if newMin != *min || newMax != *max {
+		fmt.Printf("Clamped %s range %d-%d to retained window %d-%d\n", name, *min, *max, newMin, newMax)
+	}
+	*min, *max = newMin, newMax
+}
+
+// queries the runtime's and consensus' genesis, last retained and latest
+// blocks, and clamps the random height ranges so requests don't hit pruned
+// or pre-genesis heights; flags only narrow the window further. only the
+// ranges the call samples are asked about, so consensus calls work on nodes
+// without the runtime and calls without params on any grpc server
+func ClampToRetained(ctx context.Context, call Call) error {
+	if !call.Rounds && !call.Heights {
+		return nil
+	}
+	conn, err := oasisGrpc.Dial(URL, dialOpts...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if call.Rounds {
+		client := runtime.NewRuntimeClient(conn)
+		first, err := client.GetLastRetainedBlock(ctx, RUNTIME_ID)
+		if err != nil {
+			return err
+		}
+		last, err := client.GetBlock(ctx, &runtime.GetBlockRequest{RuntimeID: RUNTIME_ID, Round: roothash.RoundLatest})
+		if err != nil {
+			return err
+		}
+		rt, err := registry.NewRegistryClient(conn).GetRuntime(ctx, &registry.GetRuntimeQuery{
+			Height: consensus.HeightLatest,
+			ID:     RUNTIME_ID,
+		})
+		if err != nil {
+			return err
+		}
+		firstRound := first.Header.Round
+		if rt.Genesis.Round > firstRound {
+			firstRound = rt.Genesis.Round
+		}
+		clamp("round", &MIN_ROUND, &MAX_ROUND, firstRound, last.Header.Round)
+	}
+
+	if call.Heights {
+		status, err := consensus.NewConsensusClient(conn).GetStatus(ctx)
+		if err != nil {
+			return err
+		}
+		firstHeight := status.LastRetainedHeight
+		if status.GenesisHeight > firstHeight {
+			firstHeight = status.GenesisHeight
+		}
+		clamp("height", &MIN_HEIGHT, &MAX_HEIGHT, uint64(firstHeight), uint64(status.LatestHeight))
+	}
+	return nil
+}
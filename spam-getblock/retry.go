@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func CheckRetry() error {
+	if RETRY_MAX_ATTEMPTS <= 1 {
+		return nil
+	}
+	// grpc caps it at 5 without saying so
+	if RETRY_MAX_ATTEMPTS > 5 {
+		return fmt.Errorf("bad -retry-max-attempts %d, grpc allows at most 5", RETRY_MAX_ATTEMPTS)
+	}
+	if RETRY_INITIAL_BACKOFF <= 0 || RETRY_MAX_BACKOFF <= 0 || RETRY_BACKOFF_MULTIPLIER <= 0 {
+		return fmt.Errorf("-retry-initial-backoff, -retry-max-backoff and -retry-backoff-multiplier must be positive")
+	}
+	for _, name := range retryCodes() {
+		var code codes.Code
+		if err := code.UnmarshalJSON([]byte(strconv.Quote(name))); err != nil {
+			return fmt.Errorf("bad -retry-codes: %w", err)
+		}
+	}
+	return nil
+}
+
+// -retry-codes as the upper case names the service config wants
+func retryCodes() []string {
+	var names []string
+	for _, s := range strings.Split(RETRY_CODES, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			names = append(names, strings.ToUpper(s))
+		}
+	}
+	return names
+}
+
+// the service config retryPolicy, nil without -retry-max-attempts
+func retryPolicy() map[string]interface{} {
+	if RETRY_MAX_ATTEMPTS <= 1 {
+		return nil
+	}
+	return map[string]interface{}{
+		"maxAttempts":          RETRY_MAX_ATTEMPTS,
+		"initialBackoff":       strconv.FormatFloat(RETRY_INITIAL_BACKOFF.Seconds(), 'f', -1, 64) + "s",
+		"maxBackoff":           strconv.FormatFloat(RETRY_MAX_BACKOFF.Seconds(), 'f', -1, 64) + "s",
+		"backoffMultiplier":    RETRY_BACKOFF_MULTIPLIER,
+		"retryableStatusCodes": retryCodes(),
+	}
+}
+
+// attempts so far of one call, shared by the contexts of all its attempts
+type rpcAttemptsKey struct{}
+
+// gives each call an attempt counter, which byteCounter.HandleRPC finds in
+// every attempt's context since they derive from the call's
+func retryDialOpts() []grpc.DialOption {
+	if RETRY_MAX_ATTEMPTS <= 1 {
+		return nil
+	}
+	add := func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, rpcAttemptsKey{}, new(int))
+	}
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(metadataInterceptor(add)),
+		grpc.WithChainStreamInterceptor(streamMetadataInterceptor(add)),
+	}
+}
+
+// requests by whether their first attempts were enough; only touched by
+// the goroutine collecting statuses
+type Retries struct {
+	Calls         int `json:"calls"`
+	Attempts      int `json:"attempts"` // calls plus retries
+	Requests      int `json:"requests"`
+	FirstAttempt  int `json:"first_attempt_ok"`
+	Retried       int `json:"retried"`
+	RetriedOk     int `json:"retried_ok"`
+	firstAttempt  Histogram
+	retriedTotals Histogram
+}
+
+var retries Retries
+
+func RecordRetries(s *ThreadStatus) {
+	if RETRY_MAX_ATTEMPTS <= 1 {
+		return
+	}
+	r := &retries
+	r.Calls += s.calls
+	r.Attempts += s.calls + s.retries
+	r.Requests++
+	switch {
+	case s.retries == 0:
+		if s.err == nil {
+			r.FirstAttempt++
+		}
+		r.firstAttempt.Add(s.times.Total())
+	default:
+		r.Retried++
+		if s.err == nil {
+			r.RetriedOk++
+		}
+		r.retriedTotals.Add(s.times.Total())
+	}
+}
+
+func RetrySummary() *Retries {
+	if RETRY_MAX_ATTEMPTS <= 1 {
+		return nil
+	}
+	return &retries
+}
+
+// amplification is attempts per call, i.e. how much more load the server
+// took than the calls alone
+func PrintRetries() {
+	r := &retries
+	if RETRY_MAX_ATTEMPTS <= 1 || r.Calls == 0 {
+		return
+	}
+	fmt.Printf("Retries: %d, amplification: %.3fx, requests retried: %d, succeeded after retrying: %d\n",
+		r.Attempts-r.Calls, float64(r.Attempts)/float64(r.Calls), r.Retried, r.RetriedOk)
+	fmt.Printf("First attempt ok: %d / %d (%.1f%%), Total: %s\n",
+		r.FirstAttempt, r.Requests, 100*float64(r.FirstAttempt)/float64(r.Requests), r.firstAttempt.String())
+	if r.Retried != 0 {
+		fmt.Printf("Retried, Total: %s\n", r.retriedTotals.String())
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	defer func(attempts int, codes string, initial, max time.Duration, multiplier float64) {
+		RETRY_MAX_ATTEMPTS, RETRY_CODES, RETRY_INITIAL_BACKOFF, RETRY_MAX_BACKOFF, RETRY_BACKOFF_MULTIPLIER = attempts, codes, initial, max, multiplier
+	}(RETRY_MAX_ATTEMPTS, RETRY_CODES, RETRY_INITIAL_BACKOFF, RETRY_MAX_BACKOFF, RETRY_BACKOFF_MULTIPLIER)
+
+	for _, tt := range []struct {
+		name     string
+		attempts int
+		codes    string
+		initial  time.Duration
+		want     map[string]interface{}
+		ok       bool
+	}{
+		{name: "off", attempts: 0, codes: "UNAVAILABLE", initial: 100 * time.Millisecond, ok: true},
+		{name: "one attempt", attempts: 1, codes: "UNAVAILABLE", initial: 100 * time.Millisecond, ok: true},
+		{name: "defaults", attempts: 3, codes: "UNAVAILABLE", initial: 100 * time.Millisecond, ok: true,
+			want: map[string]interface{}{
+				"maxAttempts":          3,
+				"initialBackoff":       "0.1s",
+				"maxBackoff":           "1s",
+				"backoffMultiplier":    2.0,
+				"retryableStatusCodes": []string{"UNAVAILABLE"},
+			}},
+		{name: "codes upper cased", attempts: 5, codes: "unavailable, resource_exhausted,", initial: 1500 * time.Millisecond, ok: true,
+			want: map[string]interface{}{
+				"maxAttempts":          5,
+				"initialBackoff":       "1.5s",
+				"maxBackoff":           "1s",
+				"backoffMultiplier":    2.0,
+				"retryableStatusCodes": []string{"UNAVAILABLE", "RESOURCE_EXHAUSTED"},
+			}},
+		{name: "too many attempts", attempts: 6, codes: "UNAVAILABLE", initial: 100 * time.Millisecond},
+		{name: "unknown code", attempts: 2, codes: "UNAVAILABLE,SOMETIMES", initial: 100 * time.Millisecond},
+		{name: "no backoff", attempts: 2, codes: "UNAVAILABLE"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			RETRY_MAX_ATTEMPTS, RETRY_CODES, RETRY_INITIAL_BACKOFF = tt.attempts, tt.codes, tt.initial
+			RETRY_MAX_BACKOFF, RETRY_BACKOFF_MULTIPLIER = time.Second, 2
+			err := CheckRetry()
+			if (err == nil) != tt.ok {
+				t.Fatalf("error %v", err)
+			}
+			if !tt.ok {
+				return
+			}
+			if got := retryPolicy(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("policy %v, expected %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	roothash "github.com/oasisprotocol/oasis-core/go/roothash/api"
+)
+
+// the roothash reads from the probe, as seen by consensus at height
+func GetRoothashState(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	client := roothash.NewRootHashClient(conn)
+	request := &roothash.RuntimeRequest{RuntimeID: RUNTIME_ID, Height: int64(height)}
+
+	start := time.Now()
+	state, err := client.GetRuntimeState(ctx, request)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.times.GetRuntimeState = time.Since(start)
+
+	start = time.Now()
+	block, err := client.GetLatestBlock(ctx, request)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.times.GetBlock = time.Since(start)
+
+	status.msg = fmt.Sprintf("Height: %d, Round: %d, Timestamp: %s, LastNormalRound: %d",
+		height, block.Header.Round, time.Unix(int64(block.Header.Timestamp), 0), state.LastNormalRound)
+	return status
+}
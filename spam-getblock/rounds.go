@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// rounds read by LoadRoundsFile, replayed in order by NextRound
+var (
+	replayRounds    []uint64
+	replayRoundNext uint64
+)
+
+// rounds read by LoadSkipRoundsFile, and how often they were requested
+// anyway (from -rounds-file or -from/-to, or by calls picking their own)
+var (
+	skipRounds     = map[uint64]bool{}
+	knownBadHits   int
+	knownBadErrors int
+)
+
+// one round/height per line of path, or stdin for "-"; blank lines and
+// lines starting with # are skipped
+func readRounds(path string) ([]uint64, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var rounds []uint64
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		round, err := strconv.ParseUint(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		rounds = append(rounds, round)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(rounds) == 0 {
+		return nil, fmt.Errorf("%s: no rounds", path)
+	}
+	return rounds, nil
+}
+
+// reads -rounds-file
+func LoadRoundsFile() error {
+	rounds, err := readRounds(ROUNDS_FILE)
+	replayRounds = rounds
+	return err
+}
+
+// reads -skip-rounds-file
+func LoadSkipRoundsFile() error {
+	rounds, err := readRounds(SKIP_ROUNDS_FILE)
+	for _, round := range rounds {
+		skipRounds[round] = true
+	}
+	return err
+}
+
+// counts a status for a known-bad round separately; true if it was one, in
+// which case its error doesn't count towards the run's errors
+func CountKnownBad(status ThreadStatus) bool {
+	if !skipRounds[status.ID] {
+		return false
+	}
+	knownBadHits++
+	if status.err != nil {
+		knownBadErrors++
+	}
+	return true
+}
+
+// the rounds from -rounds-file in order, starting over when -n is larger
+func NextRound() uint64 {
+	i := atomic.AddUint64(&replayRoundNext, 1) - 1
+	return replayRounds[i%uint64(len(replayRounds))]
+}
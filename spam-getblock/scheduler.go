@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	scheduler "github.com/oasisprotocol/oasis-core/go/scheduler/api"
+)
+
+func GetCommittees(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	start := time.Now()
+	committees, err := scheduler.NewSchedulerClient(conn).GetCommittees(ctx, &scheduler.GetCommitteesRequest{
+		Height:    int64(height),
+		RuntimeID: RUNTIME_ID,
+	})
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	num_members := 0
+	for _, committee := range committees {
+		num_members += len(committee.Members)
+	}
+	status.msg = fmt.Sprintf("Height: %d, NumCommittees: %d, NumMembers: %d", height, len(committees), num_members)
+	return status
+}
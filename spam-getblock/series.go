@@ -0,0 +1,66 @@
+package main
+
+import (
+	"time"
+)
+
+// width of the buckets in the report's time series
+const SERIES_BUCKET = 10 * time.Second
+
+// one SERIES_BUCKET of the run, by when requests finished
+type SeriesPoint struct {
+	Offset float64 `json:"offset_s"` // bucket start, from the start of the run
+	Count  uint64  `json:"count"`
+	Errors int     `json:"errors"`
+	Rate   float64 `json:"rate"`
+	P50    float64 `json:"p50_ms"`
+	P99    float64 `json:"p99_ms"`
+
+	total  Histogram
+	finish time.Time
+}
+
+// only touched by the goroutine collecting statuses
+var (
+	seriesStart time.Time
+	series      []*SeriesPoint
+)
+
+func StartSeries() {
+	seriesStart = time.Now()
+}
+
+func RecordSeries(s *ThreadStatus) {
+	if seriesStart.IsZero() {
+		return
+	}
+	now := time.Now()
+	i := int(now.Sub(seriesStart) / SERIES_BUCKET)
+	for len(series) <= i {
+		series = append(series, &SeriesPoint{Offset: (time.Duration(len(series)) * SERIES_BUCKET).Seconds()})
+	}
+	p := series[i]
+	p.total.Add(s.times.Total())
+	p.finish = now
+	if s.err != nil {
+		p.Errors++
+	}
+}
+
+// fills in the percentiles; the last bucket's rate is over the part of it
+// the run lasted
+func Series() []*SeriesPoint {
+	for i, p := range series {
+		p.Count = p.total.Count
+		p.P50 = ms(p.total.Percentile(0.5))
+		p.P99 = ms(p.total.Percentile(0.99))
+		span := SERIES_BUCKET
+		if i == len(series)-1 && !p.finish.IsZero() {
+			span = p.finish.Sub(seriesStart) - time.Duration(i)*SERIES_BUCKET
+		}
+		if span > 0 {
+			p.Rate = float64(p.Count) / span.Seconds()
+		}
+	}
+	return series
+}
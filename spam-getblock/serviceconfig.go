@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc"
+)
+
+// -lb-policy and -retry-* as the default service config, which one the
+// server sends would override
+func serviceConfigDialOpts() []grpc.DialOption {
+	config := map[string]interface{}{}
+	if LB_POLICY != "" {
+		config["loadBalancingConfig"] = []interface{}{map[string]interface{}{LB_POLICY: map[string]interface{}{}}}
+	}
+	if policy := retryPolicy(); policy != nil {
+		config["methodConfig"] = []interface{}{map[string]interface{}{
+			"name":        []interface{}{map[string]interface{}{}}, // every method
+			"retryPolicy": policy,
+		}}
+	}
+	if len(config) == 0 {
+		return nil
+	}
+	js, err := json.Marshal(config)
+	if err != nil {
+		panic(err)
+	}
+	return []grpc.DialOption{grpc.WithDefaultServiceConfig(string(js))}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// called with every finished request in completion order, by the goroutine
+// collecting statuses; outputs that stream per request register here
+var statusSinks []func(*ThreadStatus)
+
+func EmitStatus(s *ThreadStatus) {
+	for _, sink := range statusSinks {
+		sink(s)
+	}
+}
+
+// requests started but not yet finished
+var inFlight int64
+
+func InFlight() int64 {
+	return atomic.LoadInt64(&inFlight)
+}
+
+// runs call_f counting it as in flight
+func track(f func() ThreadStatus) ThreadStatus {
+	atomic.AddInt64(&inFlight, 1)
+	defer atomic.AddInt64(&inFlight, -1)
+	return f()
+}
+
+// records a finished request everywhere and prints it unless the tui owns
+// the terminal; true if it counts as a failed request
+func CollectStatus(status ThreadStatus) bool {
+	latencies.Record(&status.times)
+	RecordBandwidth(&status)
+	RecordSeries(&status)
+	RecordEndpoint(&status)
+	RecordPoolConn(&status)
+	RecordBackend(&status)
+	RecordIpFamily(&status)
+	RecordApdex(&status)
+	RecordSlowest(&status)
+	RecordMetadata(&status)
+	RecordConnTimes(&status)
+	RecordDisconnect(&status)
+	RecordCompression(&status)
+	RecordQueued(&status)
+	RecordRetries(&status)
+	RecordHedge(&status)
+	RecordReconnect(&status)
+	EmitStatus(&status)
+	knownBad := CountKnownBad(status)
+	if !TUI {
+		// if loglevel=blockdata
+		fmt.Println(status.msg)
+		if status.err != nil {
+			fmt.Printf("thread %d: %s\n", status.ID, status.err)
+		}
+	}
+	if status.err == nil || knownBad {
+		return false
+	}
+	CountError(&status)
+	return true
+}
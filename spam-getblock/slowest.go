@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// one of the -slowest requests, with enough detail to tell whether the
+// tail is always the same rounds, endpoint or connection
+type SlowRequest struct {
+	Round    uint64             `json:"round"`
+	Total    float64            `json:"total_ms"`
+	Times    map[string]float64 `json:"times_ms"`
+	Bytes    int64              `json:"bytes"`
+	RpcBytes map[string]int64   `json:"rpc_bytes"`
+	Endpoint string             `json:"endpoint"`
+	Conn     string             `json:"conn"`
+	Code     string             `json:"code"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// slowest first, at most SLOWEST; only touched by the goroutine collecting
+// statuses
+var slowest []SlowRequest
+
+func RecordSlowest(s *ThreadStatus) {
+	total := ms(s.times.Total())
+	if SLOWEST <= 0 || (len(slowest) == SLOWEST && total <= slowest[len(slowest)-1].Total) {
+		return
+	}
+	r := SlowRequest{
+		Round:    s.ID,
+		Total:    total,
+		Times:    map[string]float64{"Connect": ms(s.times.Connect)},
+		Bytes:    s.bytes,
+		RpcBytes: map[string]int64{},
+		Endpoint: s.endpoint,
+		Conn:     s.conn,
+		Code:     errorCode(s.err),
+	}
+	for _, phase := range s.times.Phases() {
+		if phase.Duration != 0 {
+			r.Times[phase.Name] = ms(phase.Duration)
+		}
+	}
+	for method, size := range s.rpcs {
+		r.RpcBytes[method] = size.Bytes
+	}
+	if s.err != nil {
+		r.Error = s.err.Error()
+	}
+	i := sort.Search(len(slowest), func(i int) bool { return slowest[i].Total < total })
+	slowest = append(slowest, SlowRequest{})
+	copy(slowest[i+1:], slowest[i:])
+	slowest[i] = r
+	if len(slowest) > SLOWEST {
+		slowest = slowest[:SLOWEST]
+	}
+}
+
+func PrintSlowest() {
+	if len(slowest) == 0 {
+		return
+	}
+	fmt.Println("Slowest:")
+	for _, r := range slowest {
+		fmt.Printf("\t%d: %.1fms, %d bytes, %s %s", r.Round, r.Total, r.Bytes, r.Endpoint, r.Conn)
+		if r.Error != "" {
+			fmt.Printf(", %s", r.Code)
+		}
+		fmt.Println()
+		phases := make([]string, 0, len(r.Times))
+		for name := range r.Times {
+			phases = append(phases, name)
+		}
+		sort.Slice(phases, func(i, j int) bool { return r.Times[phases[i]] > r.Times[phases[j]] })
+		fmt.Print("\t\t")
+		for i, name := range phases {
+			if i != 0 {
+				fmt.Print(", ")
+			}
+			fmt.Printf("%s: %.1fms", name, r.Times[name])
+		}
+		fmt.Println()
+		if len(r.RpcBytes) != 0 {
+			methods := make([]string, 0, len(r.RpcBytes))
+			for method := range r.RpcBytes {
+				methods = append(methods, method)
+			}
+			sort.Strings(methods)
+			fmt.Print("\t\tBytes")
+			for _, method := range methods {
+				fmt.Printf(" %s: %d", method, r.RpcBytes[method])
+			}
+			fmt.Println()
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecordSlowest(t *testing.T) {
+	defer func(n int, s []SlowRequest) { SLOWEST, slowest = n, s }(SLOWEST, slowest)
+
+	for _, tt := range []struct {
+		name  string
+		limit int
+		ms    []int
+		want  []uint64 // rounds, slowest first
+	}{
+		{"off", 0, []int{5, 1}, nil},
+		{"under the limit", 3, []int{5, 1}, []uint64{0, 1}},
+		{"sorted", 3, []int{1, 3, 2}, []uint64{1, 2, 0}},
+		{"evicts the fastest", 2, []int{1, 3, 2, 4}, []uint64{3, 1}},
+		{"ties keep the first", 2, []int{2, 2, 2}, []uint64{0, 1}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			SLOWEST, slowest = tt.limit, nil
+			for i, ms := range tt.ms {
+				RecordSlowest(&ThreadStatus{ID: uint64(i), times: ApiTimes{GetBlock: time.Duration(ms) * time.Millisecond}})
+			}
+			var got []uint64
+			for _, r := range slowest {
+				got = append(got, r.Round)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("rounds %v, expected %v", got, tt.want)
+			}
+		})
+	}
+}
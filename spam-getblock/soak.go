@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	oasisGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	runtime "github.com/oasisprotocol/oasis-core/go/runtime/client/api"
+)
+
+type Subscriber struct {
+	ID         int
+	Blocks     int
+	Dropped    int // streams that closed before the end of the run
+	Reconnects int
+	Errors     int           // failed (re)subscriptions and backfills
+	Missed     int           // rounds skipped by the stream and backfilled with GetBlock
+	Recovery   DurationStats // from a drop until the stream is caught up again
+	Lag        DurationStats // receipt time minus block timestamp
+	Behind     DurationStats // receipt time minus the first subscriber's receipt of the same round
+}
+
+func (s *Subscriber) String() string {
+	return fmt.Sprintf("subscriber %d: Blocks: %d, Dropped: %d, Reconnects: %d, Errors: %d, Missed: %d, Lag: {%s}, Behind: {%s}, Recovery: {%s}",
+		s.ID, s.Blocks, s.Dropped, s.Reconnects, s.Errors, s.Missed, s.Lag.String(), s.Behind.String(), s.Recovery.String())
+}
+
+// first receipt time of each round across all subscribers
+type firstSeen struct {
+	sync.Mutex
+	rounds map[uint64]time.Time
+}
+
+func (f *firstSeen) See(round uint64, t time.Time) time.Duration {
+	f.Lock()
+	defer f.Unlock()
+	first, ok := f.rounds[round]
+	if !ok {
+		f.rounds[round] = t
+		return 0
+	}
+	return t.Sub(first)
+}
+
+// fetches the runtime blocks [from, to) that a stream skipped, returning
+// how many were fetched
+func BackfillRuntime(ctx context.Context, client runtime.RuntimeClient, from, to uint64) (int, error) {
+	n := 0
+	for round := from; round < to; round++ {
+		_, err := client.GetBlock(ctx, &runtime.GetBlockRequest{
+			RuntimeID: RUNTIME_ID,
+			Round:     round,
+		})
+		if err != nil {
+			return n, fmt.Errorf("backfill round %d: %w", round, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// one runtime WatchBlocks stream on its own connection, resubscribing
+// whenever it drops until ctx is done and backfilling the rounds it missed
+func (s *Subscriber) Run(ctx context.Context, seen *firstSeen) {
+	var lastRound uint64
+	var droppedAt time.Time
+	for first := true; ctx.Err() == nil; first = false {
+		if !first {
+			s.Reconnects++
+		}
+		conn, err := oasisGrpc.Dial(URL, dialOpts...)
+		if err != nil {
+			s.Errors++
+			time.Sleep(time.Second)
+			continue
+		}
+		WatchState(conn, fmt.Sprintf("subscriber %d", s.ID))
+		client := runtime.NewRuntimeClient(conn)
+		blocks, sub, err := client.WatchBlocks(ctx, RUNTIME_ID)
+		if err != nil {
+			s.Errors++
+			conn.Close()
+			time.Sleep(time.Second)
+			continue
+		}
+		for blk := range blocks {
+			received := time.Now()
+			round := blk.Block.Header.Round
+			if lastRound != 0 && round > lastRound+1 {
+				n, err := BackfillRuntime(ctx, client, lastRound+1, round)
+				s.Missed += n
+				if err != nil {
+					s.Errors++
+				}
+			}
+			if !droppedAt.IsZero() {
+				s.Recovery.Add(time.Since(droppedAt))
+				droppedAt = time.Time{}
+			}
+			if round <= lastRound {
+				// resent on resubscription
+				continue
+			}
+			lastRound = round
+			s.Blocks++
+			s.Lag.Add(received.Sub(time.Unix(int64(blk.Block.Header.Timestamp), 0)))
+			s.Behind.Add(seen.See(round, received))
+		}
+		if ctx.Err() == nil {
+			s.Dropped++
+			droppedAt = time.Now()
+		}
+		sub.Close()
+		conn.Close()
+	}
+}
+
+// keeps SUBSCRIBERS runtime block streams open for DURATION to find the
+// node's fan-out limits
+func RunSoak(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, DURATION)
+	defer cancel()
+
+	seen := &firstSeen{rounds: map[uint64]time.Time{}}
+	subscribers := make([]*Subscriber, SUBSCRIBERS)
+	wg := sync.WaitGroup{}
+	for i := range subscribers {
+		subscribers[i] = &Subscriber{ID: i}
+		wg.Add(1)
+		go func(s *Subscriber) {
+			defer wg.Done()
+			s.Run(ctx, seen)
+		}(subscribers[i])
+	}
+	wg.Wait()
+
+	total := Subscriber{ID: -1}
+	for _, s := range subscribers {
+		fmt.Println(s.String())
+		total.Blocks += s.Blocks
+		total.Dropped += s.Dropped
+		total.Reconnects += s.Reconnects
+		total.Errors += s.Errors
+		total.Missed += s.Missed
+	}
+	fmt.Println("Subscribers:", SUBSCRIBERS, "Rounds:", len(seen.rounds))
+	fmt.Println("Blocks:", total.Blocks, "Dropped:", total.Dropped, "Reconnects:", total.Reconnects, "Errors:", total.Errors, "Missed:", total.Missed)
+	return nil
+}
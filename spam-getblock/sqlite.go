@@ -0,0 +1,197 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// rows per transaction, so a crashed run still leaves most of its rows
+const SQLITE_BATCH = 1000
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	run_id        TEXT PRIMARY KEY,
+	started       TEXT NOT NULL,
+	call          TEXT NOT NULL,
+	endpoint      TEXT NOT NULL,
+	runtime       TEXT NOT NULL,
+	seed          INTEGER NOT NULL,
+	requests      INTEGER,
+	errors        INTEGER,
+	total_seconds REAL,
+	rate          REAL,
+	p50_ms        REAL,
+	p99_ms        REAL,
+	summary       TEXT
+);
+CREATE TABLE IF NOT EXISTS requests (
+	run_id    TEXT NOT NULL REFERENCES runs(run_id),
+	finished  TEXT NOT NULL,
+	round     INTEGER NOT NULL,
+	total_ms  REAL NOT NULL,
+	connect_ms REAL NOT NULL,
+	bytes     INTEGER NOT NULL,
+	code      TEXT NOT NULL,
+	error     TEXT
+);
+CREATE INDEX IF NOT EXISTS requests_run_id ON requests(run_id);
+CREATE INDEX IF NOT EXISTS runs_endpoint ON runs(endpoint, call);
+`
+
+var (
+	sqliteDB   *sql.DB
+	sqliteTx   *sql.Tx
+	sqliteStmt *sql.Stmt
+	sqliteRows int
+)
+
+func sqliteBegin() error {
+	var err error
+	if sqliteTx, err = sqliteDB.Begin(); err != nil {
+		return err
+	}
+	sqliteStmt, err = sqliteTx.Prepare(`INSERT INTO requests (run_id, finished, round, total_ms, connect_ms, bytes, code, error) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	return err
+}
+
+// opens (creating if needed) -sqlite and adds this run; requests are
+// appended as they finish
+func OpenSqlite() error {
+	db, err := sql.Open("sqlite3", SQLITE)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return err
+	}
+	_, err = db.Exec(`INSERT INTO runs (run_id, started, call, endpoint, runtime, seed) VALUES (?, ?, ?, ?, ?, ?)`,
+		RUN_ID, time.Now().UTC().Format(time.RFC3339), CALL, URL, RUNTIME, SEED)
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("run %s: %w", RUN_ID, err)
+	}
+	sqliteDB = db
+	if err := sqliteBegin(); err != nil {
+		return err
+	}
+	statusSinks = append(statusSinks, WriteSqliteRow)
+	return nil
+}
+
+func WriteSqliteRow(s *ThreadStatus) {
+	var errStr sql.NullString
+	if s.err != nil {
+		errStr = sql.NullString{String: s.err.Error(), Valid: true}
+	}
+	_, err := sqliteStmt.Exec(RUN_ID, time.Now().UTC().Format(time.RFC3339Nano), int64(s.ID),
+		ms(s.times.Total()), ms(s.times.Connect), s.bytes, errorCode(s.err), errStr)
+	if err == nil {
+		sqliteRows++
+		if sqliteRows%SQLITE_BATCH == 0 {
+			if err = sqliteTx.Commit(); err == nil {
+				err = sqliteBegin()
+			}
+		}
+	}
+	if err != nil {
+		fmt.Println("SQLite error:", err)
+	}
+}
+
+// fills in the run's aggregates, in the open batch since sqlite allows
+// only one writer
+func WriteSqliteRun(s *Summary) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	_, err = sqliteTx.Exec(`UPDATE runs SET requests = ?, errors = ?, total_seconds = ?, rate = ?, p50_ms = ?, p99_ms = ?, summary = ? WHERE run_id = ?`,
+		s.Requests, s.Errors, s.TotalSeconds, s.Rate, s.Total.P50, s.Total.P99, string(b), RUN_ID)
+	return err
+}
+
+// commits the last batch
+func CloseSqlite() {
+	if sqliteDB == nil {
+		return
+	}
+	if err := sqliteTx.Commit(); err != nil {
+		fmt.Println("SQLite error:", err)
+	}
+	sqliteDB.Close()
+}
+
+// grpc-test report -db results.db: historical runs per endpoint and call
+func RunReport(args []string) error {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	db := flags.String("db", "", "sqlite database written with -sqlite")
+	last := flags.Int("last", 10, "recent runs to list per endpoint and call")
+	flags.Parse(args)
+	if *db == "" {
+		return fmt.Errorf("-db is required")
+	}
+	if _, err := os.Stat(*db); err != nil {
+		return err
+	}
+	conn, err := sql.Open("sqlite3", *db)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	rows, err := conn.Query(`SELECT endpoint, call, COUNT(*), SUM(requests), SUM(errors), AVG(rate), AVG(p99_ms), MIN(started), MAX(started)
+		FROM runs WHERE summary IS NOT NULL GROUP BY endpoint, call ORDER BY endpoint, call`)
+	if err != nil {
+		return err
+	}
+	type group struct{ endpoint, call string }
+	var groups []group
+	for rows.Next() {
+		var g group
+		var runs, requests, errors int64
+		var rate, p99 float64
+		var first, latest string
+		if err := rows.Scan(&g.endpoint, &g.call, &runs, &requests, &errors, &rate, &p99, &first, &latest); err != nil {
+			rows.Close()
+			return err
+		}
+		groups = append(groups, g)
+		fmt.Printf("%s %s: Runs: %d (%s to %s), Requests: %d, Errors: %d, Mean rate: %.1f /s, Mean p99: %.1fms\n",
+			g.endpoint, g.call, runs, first, latest, requests, errors, rate, p99)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, g := range groups {
+		rows, err := conn.Query(`SELECT run_id, started, requests, errors, rate, p50_ms, p99_ms FROM runs
+			WHERE endpoint = ? AND call = ? AND summary IS NOT NULL ORDER BY started DESC LIMIT ?`, g.endpoint, g.call, *last)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var runID, started string
+			var requests, errors int64
+			var rate, p50, p99 float64
+			if err := rows.Scan(&runID, &started, &requests, &errors, &rate, &p50, &p99); err != nil {
+				rows.Close()
+				return err
+			}
+			fmt.Printf("  %s %s: Requests: %d, Errors: %d, Rate: %.1f /s, p50: %.1fms, p99: %.1fms\n",
+				started, runID, requests, errors, rate, p50, p99)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
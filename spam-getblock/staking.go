@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	oasisGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+// number of addresses sampled from the node when -address is not given
+const NUM_SAMPLED_ADDRESSES = 1000
+
+// set by LoadStakingAddresses, once per runtime with -all-runtimes
+var stakingAddresses []staking.Address
+
+// parses -address and -addresses-file, or if both are empty samples
+// addresses known to the node at the latest height
+func LoadStakingAddresses(ctx context.Context) error {
+	list, err := AddressList()
+	if err != nil {
+		return err
+	}
+	stakingAddresses = nil
+	resetAddressSampling()
+	if len(list) != 0 {
+		for _, s := range list {
+			var address staking.Address
+			if err := address.UnmarshalText([]byte(s)); err != nil {
+				return fmt.Errorf("bad address '%s': %w", s, err)
+			}
+			stakingAddresses = append(stakingAddresses, address)
+		}
+		return nil
+	}
+
+	conn, err := oasisGrpc.Dial(URL, dialOpts...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	addresses, err := staking.NewStakingClient(conn).Addresses(ctx, consensus.HeightLatest)
+	if err != nil {
+		return err
+	}
+	if len(addresses) == 0 {
+		return fmt.Errorf("node returned no staking addresses")
+	}
+	rand.Shuffle(len(addresses), func(i, j int) { addresses[i], addresses[j] = addresses[j], addresses[i] })
+	if len(addresses) > NUM_SAMPLED_ADDRESSES {
+		addresses = addresses[:NUM_SAMPLED_ADDRESSES]
+	}
+	stakingAddresses = addresses
+	fmt.Println("Sampled", len(stakingAddresses), "staking addresses")
+	return nil
+}
+
+func PickStakingAddress() staking.Address {
+	return stakingAddresses[PickAddressIndex(len(stakingAddresses))]
+}
+
+func GetStakingAccount(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	address := PickStakingAddress()
+	start := time.Now()
+	account, err := staking.NewStakingClient(conn).Account(ctx, &staking.OwnerQuery{Height: int64(height), Owner: address})
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("Height: %d, Address: %s, Balance: %s, Nonce: %d", height, address, account.General.Balance, account.General.Nonce)
+	return status
+}
+
+func GetDelegationsFor(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	address := PickStakingAddress()
+	start := time.Now()
+	delegations, err := staking.NewStakingClient(conn).DelegationsFor(ctx, &staking.OwnerQuery{Height: int64(height), Owner: address})
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("Height: %d, Address: %s, NumDelegations: %d", height, address, len(delegations))
+	return status
+}
+
+func GetDebondingDelegationsFor(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	address := PickStakingAddress()
+	start := time.Now()
+	debondings, err := staking.NewStakingClient(conn).DebondingDelegationsFor(ctx, &staking.OwnerQuery{Height: int64(height), Owner: address})
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	num_debondings := 0
+	for _, ds := range debondings {
+		num_debondings += len(ds)
+	}
+	status.msg = fmt.Sprintf("Height: %d, Address: %s, NumEscrows: %d, NumDebondingDelegations: %d", height, address, len(debondings), num_debondings)
+	return status
+}
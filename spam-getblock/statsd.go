@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+var statsdConn net.Conn
+
+// dials -statsd over udp; sends are fire and forget like any statsd client
+func OpenStatsd() error {
+	if STATSD_FORMAT != "statsd" && STATSD_FORMAT != "dogstatsd" {
+		return fmt.Errorf("bad -statsd-format '%s', expected statsd or dogstatsd", STATSD_FORMAT)
+	}
+	conn, err := net.Dial("udp", STATSD)
+	if err != nil {
+		return err
+	}
+	statsdConn = conn
+	statusSinks = append(statusSinks, SendStatsd)
+	return nil
+}
+
+// plain statsd has no tags, so they go into the metric name instead
+func statsdLine(name, value, kind string, tags ...string) string {
+	if STATSD_FORMAT == "dogstatsd" {
+		line := fmt.Sprintf("%s.%s:%s|%s", STATSD_PREFIX, name, value, kind)
+		if len(tags) != 0 {
+			line += "|#" + strings.Join(tags, ",")
+		}
+		return line
+	}
+	for _, tag := range tags {
+		name += "." + strings.NewReplacer(":", "_", ".", "_").Replace(tag)
+	}
+	return fmt.Sprintf("%s.%s:%s|%s", STATSD_PREFIX, name, value, kind)
+}
+
+func statsdTiming(name string, d time.Duration, tags ...string) string {
+	return statsdLine(name, fmt.Sprintf("%.3f", ms(d)), "ms", tags...)
+}
+
+// one packet per request: a counter, the error code if any, and a timing per phase
+func SendStatsd(s *ThreadStatus) {
+	call := "call:" + CALL
+	lines := []string{
+		statsdLine("requests", "1", "c", call),
+		statsdTiming("phase.Connect", s.times.Connect, call),
+	}
+	if s.err != nil {
+		lines = append(lines, statsdLine("errors", "1", "c", call, "code:"+errorCode(s.err)))
+	}
+	for _, phase := range s.times.Phases() {
+		if phase.Duration != 0 {
+			lines = append(lines, statsdTiming("phase."+phase.Name, phase.Duration, call))
+		}
+	}
+	lines = append(lines, statsdTiming("total", s.times.Total(), call))
+	statsdConn.Write([]byte(strings.Join(lines, "\n")))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	runtime "github.com/oasisprotocol/oasis-core/go/runtime/client/api"
+	storage "github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/syncer"
+)
+
+// max number of entries returned by SyncGetPrefixes
+const SYNC_GET_PREFIXES_LIMIT = 100
+
+func ProofSize(proof *syncer.Proof) (size int) {
+	for _, entry := range proof.Entries {
+		size += len(entry)
+	}
+	return size
+}
+
+// raw read-syncer requests against the runtime state root of the round; this
+// is the layer GetTransactions and runtime queries are built on
+func SyncStorage(ctx context.Context, round uint64) ThreadStatus {
+	status := ThreadStatus{ID: round, times: ApiTimes{}}
+	key, err := hex.DecodeString(STORAGE_KEY)
+	if err != nil {
+		status.err = fmt.Errorf("bad -storage-key: %w", err)
+		return status
+	}
+	prefix, err := hex.DecodeString(STORAGE_PREFIX)
+	if err != nil {
+		status.err = fmt.Errorf("bad -storage-prefix: %w", err)
+		return status
+	}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	start := time.Now()
+	block, err := runtime.NewRuntimeClient(conn).GetBlock(ctx, &runtime.GetBlockRequest{
+		RuntimeID: RUNTIME_ID,
+		Round:     round,
+	})
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.times.GetBlock = time.Since(start)
+
+	client := storage.NewStorageClient(conn)
+	tree := syncer.TreeID{Root: block.Header.StorageRoots()[1]}
+
+	start = time.Now()
+	get, err := client.SyncGet(ctx, &syncer.GetRequest{Tree: tree, Key: key})
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.times.SyncGet = time.Since(start)
+
+	start = time.Now()
+	prefixes, err := client.SyncGetPrefixes(ctx, &syncer.GetPrefixesRequest{
+		Tree:     tree,
+		Prefixes: [][]byte{prefix},
+		Limit:    SYNC_GET_PREFIXES_LIMIT,
+	})
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.times.SyncGetPrefixes = time.Since(start)
+
+	status.msg = fmt.Sprintf("Round: %d, SyncGet: %d entries %d bytes, SyncGetPrefixes: %d entries %d bytes",
+		round, len(get.Proof.Entries), ProofSize(&get.Proof), len(prefixes.Proof.Entries), ProofSize(&prefixes.Proof))
+	return status
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// requests that waited longer than this for a stream count as queued
+const STREAM_QUEUE_THRESHOLD = time.Millisecond
+
+// rpcs between their headers going out and their end, across connections;
+// on one connection this is what the server's MAX_CONCURRENT_STREAMS caps
+var activeStreams, peakStreams int64
+
+func streamOpened() {
+	n := atomic.AddInt64(&activeStreams, 1)
+	for {
+		peak := atomic.LoadInt64(&peakStreams)
+		if n <= peak || atomic.CompareAndSwapInt64(&peakStreams, peak, n) {
+			return
+		}
+	}
+}
+
+func streamClosed() {
+	atomic.AddInt64(&activeStreams, -1)
+}
+
+// one step of the ramp
+type streamLevel struct {
+	Concurrency int
+	Requests    int
+	Errors      int
+	Queued      int // waited over STREAM_QUEUE_THRESHOLD for a stream
+	PeakStreams int64
+	Wait        Histogram
+	Total       Histogram
+}
+
+func (l *streamLevel) Queueing() bool {
+	return l.Requests != 0 && float64(l.Queued)/float64(l.Requests) > 0.1
+}
+
+func (l *streamLevel) String() string {
+	return fmt.Sprintf("Concurrency: %d, Requests: %d, Errors: %d, Queued: %d, Peak streams: %d, Wait p50: %s, p99: %s, Total p50: %s, p99: %s",
+		l.Concurrency, l.Requests, l.Errors, l.Queued, l.PeakStreams,
+		l.Wait.Percentile(0.5), l.Wait.Percentile(0.99), l.Total.Percentile(0.5), l.Total.Percentile(0.99))
+}
+
+// runs concurrency workers calling call_f back to back on the shared
+// connection for STREAM_LIMIT_STEP, collecting every status
+func runStreamLevel(ctx context.Context, concurrency int,
+	call_f func(context.Context, uint64) ThreadStatus,
+	parameter_f func() uint64,
+) *streamLevel {
+	level := &streamLevel{Concurrency: concurrency}
+	atomic.StoreInt64(&peakStreams, atomic.LoadInt64(&activeStreams))
+	ch := make(chan ThreadStatus, concurrency)
+	deadline := time.Now().Add(STREAM_LIMIT_STEP)
+	var params sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				params.Lock()
+				height := parameter_f()
+				params.Unlock()
+				subctx, cancel := context.WithTimeout(WithStatusSlot(ctx), TIMEOUT)
+				ch <- track(func() ThreadStatus { return call_f(subctx, height) })
+				cancel()
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+	for status := range ch {
+		level.Requests++
+		if status.queued > STREAM_QUEUE_THRESHOLD {
+			level.Queued++
+		}
+		level.Wait.Add(status.queued)
+		level.Total.Add(status.times.Total())
+		if CollectStatus(status) {
+			level.Errors++
+		}
+	}
+	level.PeakStreams = atomic.LoadInt64(&peakStreams)
+	return level
+}
+
+// doubles the requests in flight on one connection until over a tenth of
+// them wait for a stream, then reports the peak streams the server allowed
+// and what waiting cost compared to the last level that didn't
+func FindStreamLimit(ctx context.Context,
+	call_f func(context.Context, uint64) ThreadStatus,
+	parameter_f func() uint64,
+) (num_requests, num_errors int) {
+	var levels []*streamLevel
+	for concurrency := 1; concurrency <= STREAM_LIMIT_MAX; concurrency *= 2 {
+		level := runStreamLevel(ctx, concurrency, call_f, parameter_f)
+		num_requests += level.Requests
+		num_errors += level.Errors
+		levels = append(levels, level)
+		if level.Queueing() {
+			break
+		}
+	}
+
+	fmt.Println("Stream limit ramp:")
+	for _, level := range levels {
+		fmt.Println("\t" + level.String())
+	}
+	last := levels[len(levels)-1]
+	if !last.Queueing() {
+		fmt.Println("No queueing up to", last.Concurrency, "concurrent requests; the limit is higher, raise -stream-limit-max")
+		return
+	}
+	fmt.Println("Stream limit:", last.PeakStreams)
+	if len(levels) < 2 {
+		return
+	}
+	prev := levels[len(levels)-2]
+	fmt.Printf("Over the limit: wait p50: %s, p99: %s, total p50: %s (%+.1f%%), p99: %s (%+.1f%%)\n",
+		last.Wait.Percentile(0.5), last.Wait.Percentile(0.99),
+		last.Total.Percentile(0.5), percentChange(ms(prev.Total.Percentile(0.5)), ms(last.Total.Percentile(0.5))),
+		last.Total.Percentile(0.99), percentChange(ms(prev.Total.Percentile(0.99)), ms(last.Total.Percentile(0.99))))
+	return
+}
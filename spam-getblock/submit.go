@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+	"github.com/oasisprotocol/oasis-core/go/consensus/api/transaction"
+	runtime "github.com/oasisprotocol/oasis-core/go/runtime/client/api"
+)
+
+// pre-signed transactions read by LoadTransactions, each submitted once
+var (
+	submitTxs    [][]byte
+	submitTxNext uint64
+)
+
+// reads -tx-file: one hex encoded CBOR transaction per line, blank lines and
+// lines starting with # are skipped
+func LoadTransactions(ctx context.Context) error {
+	if TX_LAYER != "consensus" && TX_LAYER != "runtime" {
+		return fmt.Errorf("bad -tx-layer '%s', expected consensus or runtime", TX_LAYER)
+	}
+	if TX_FILE == "" {
+		return fmt.Errorf("-tx-file is required")
+	}
+	f, err := os.Open(TX_FILE)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	submitTxs = nil
+	atomic.StoreUint64(&submitTxNext, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		tx, err := hex.DecodeString(text)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", TX_FILE, line, err)
+		}
+		submitTxs = append(submitTxs, tx)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(submitTxs) < NUM_REQUESTS {
+		fmt.Println("Warning: only", len(submitTxs), "transactions for", NUM_REQUESTS, "requests; the rest will fail")
+	}
+	return nil
+}
+
+// index of the next unsubmitted transaction
+func NextTransaction() uint64 {
+	return atomic.AddUint64(&submitTxNext, 1) - 1
+}
+
+func SubmitTransaction(ctx context.Context, i uint64) ThreadStatus {
+	status := ThreadStatus{ID: i, times: ApiTimes{}}
+	if i >= uint64(len(submitTxs)) {
+		status.err = fmt.Errorf("out of transactions")
+		return status
+	}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	start := time.Now()
+	switch TX_LAYER {
+	case "consensus":
+		var tx transaction.SignedTransaction
+		if err = cbor.Unmarshal(submitTxs[i], &tx); err != nil {
+			status.err = fmt.Errorf("tx %d: %w", i, err)
+			return status
+		}
+		start = time.Now()
+		err = consensus.NewConsensusClient(conn).SubmitTxNoWait(ctx, &tx)
+	case "runtime":
+		err = runtime.NewRuntimeClient(conn).SubmitTxNoWait(ctx, &runtime.SubmitTxRequest{
+			RuntimeID: RUNTIME_ID,
+			Data:      submitTxs[i],
+		})
+	}
+	status.times.Query = time.Since(start)
+	if err != nil {
+		// CheckTx failures (mempool rejections) end up here too
+		status.err = err
+		return status
+	}
+	status.msg = fmt.Sprintf("Tx: %d, Layer: %s, Size: %d bytes, accepted", i, TX_LAYER, len(submitTxs[i]))
+	return status
+}
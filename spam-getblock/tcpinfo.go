@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// connections with the most retransmits kept in the report
+const NUM_TCP_CONNS = 20
+
+// one TCP_INFO sample, taken when the connection closes or, if it's still
+// open, when the report is made
+type TcpConnInfo struct {
+	Remote      string  `json:"remote"`
+	Local       string  `json:"local"`
+	Rtt         float64 `json:"rtt_ms"` // smoothed
+	RttVar      float64 `json:"rtt_var_ms"`
+	MinRtt      float64 `json:"min_rtt_ms"`
+	Retransmits uint32  `json:"retransmits"`
+	SegsOut     uint32  `json:"segs_out"`
+	Lifetime    float64 `json:"lifetime_ms"`
+}
+
+type TcpInfoSummary struct {
+	Connections int               `json:"connections"`
+	Retransmits uint64            `json:"retransmits"`
+	SegsOut     uint64            `json:"segs_out"`
+	Rtt         PercentileSummary `json:"rtt"` // by connection
+	Worst       []TcpConnInfo     `json:"worst"`
+	rtt         Histogram
+}
+
+// the dialed socket, kept so TCP_INFO can be read off it before close
+type tcpConn struct {
+	net.Conn
+	opened time.Time
+	once   sync.Once
+}
+
+var (
+	tcpMutex  sync.Mutex
+	openTcp   = map[*tcpConn]struct{}{}
+	closedTcp TcpInfoSummary
+)
+
+func CheckTcpInfo() error {
+	if TCP_INFO && runtime.GOOS != "linux" {
+		return fmt.Errorf("-tcp-info needs linux")
+	}
+	return nil
+}
+
+// wraps what the dialers return; unix sockets have no TCP_INFO
+func trackTcp(conn net.Conn) net.Conn {
+	if !TCP_INFO || conn.RemoteAddr().Network() != "tcp" {
+		return conn
+	}
+	c := &tcpConn{Conn: conn, opened: time.Now()}
+	tcpMutex.Lock()
+	openTcp[c] = struct{}{}
+	tcpMutex.Unlock()
+	return c
+}
+
+func (c *tcpConn) Close() error {
+	c.once.Do(func() {
+		info, ok := c.sample()
+		tcpMutex.Lock()
+		delete(openTcp, c)
+		if ok {
+			closedTcp.add(info)
+		}
+		tcpMutex.Unlock()
+	})
+	return c.Conn.Close()
+}
+
+func (c *tcpConn) sample() (TcpConnInfo, bool) {
+	// through an http proxy the socket is under the CONNECT reader
+	conn := c.Conn
+	if b, ok := conn.(*bufferedConn); ok {
+		conn = b.Conn
+	}
+	info, ok := readTcpInfo(conn)
+	info.Remote = c.RemoteAddr().String()
+	info.Local = c.LocalAddr().String()
+	info.Lifetime = ms(time.Since(c.opened))
+	return info, ok
+}
+
+func (s *TcpInfoSummary) add(info TcpConnInfo) {
+	s.Connections++
+	s.Retransmits += uint64(info.Retransmits)
+	s.SegsOut += uint64(info.SegsOut)
+	s.rtt.Add(time.Duration(info.Rtt * float64(time.Millisecond)))
+	if len(s.Worst) == NUM_TCP_CONNS && info.Retransmits <= s.Worst[len(s.Worst)-1].Retransmits {
+		return
+	}
+	i := sort.Search(len(s.Worst), func(i int) bool { return s.Worst[i].Retransmits < info.Retransmits })
+	s.Worst = append(s.Worst, TcpConnInfo{})
+	copy(s.Worst[i+1:], s.Worst[i:])
+	s.Worst[i] = info
+	if len(s.Worst) > NUM_TCP_CONNS {
+		s.Worst = s.Worst[:NUM_TCP_CONNS]
+	}
+}
+
+// closed connections plus a fresh sample of the open ones, like the shared
+// connections that last the whole run
+func TcpInfo() *TcpInfoSummary {
+	if !TCP_INFO {
+		return nil
+	}
+	tcpMutex.Lock()
+	s := closedTcp
+	s.Worst = append([]TcpConnInfo(nil), closedTcp.Worst...)
+	s.rtt.counts = append([]uint64(nil), closedTcp.rtt.counts...)
+	open := make([]*tcpConn, 0, len(openTcp))
+	for c := range openTcp {
+		open = append(open, c)
+	}
+	tcpMutex.Unlock()
+	for _, c := range open {
+		if info, ok := c.sample(); ok {
+			s.add(info)
+		}
+	}
+	s.Rtt = s.rtt.Summary()
+	return &s
+}
+
+// retransmits and a high RTT point at the network path rather than the
+// server
+func PrintTcpInfo() {
+	s := TcpInfo()
+	if s == nil || s.Connections == 0 {
+		return
+	}
+	fmt.Printf("TCP connections: %d, retransmits: %d / %d segments", s.Connections, s.Retransmits, s.SegsOut)
+	if s.SegsOut != 0 {
+		fmt.Printf(" (%.2f%%)", 100*float64(s.Retransmits)/float64(s.SegsOut))
+	}
+	fmt.Printf(", RTT: %s\n", s.rtt.String())
+	for _, c := range s.Worst {
+		fmt.Printf("\t%s -> %s: RTT %.2fms ±%.2fms (min %.2fms), retransmits %d / %d, open %.0fms\n",
+			c.Local, c.Remote, c.Rtt, c.RttVar, c.MinRtt, c.Retransmits, c.SegsOut, c.Lifetime)
+	}
+}
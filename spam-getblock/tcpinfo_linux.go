@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func readTcpInfo(conn net.Conn) (TcpConnInfo, bool) {
+	var info TcpConnInfo
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return info, false
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return info, false
+	}
+	var ti *unix.TCPInfo
+	var serr error
+	if err := raw.Control(func(fd uintptr) {
+		ti, serr = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+	}); err != nil || serr != nil {
+		return info, false
+	}
+	// the kernel keeps times in microseconds
+	info.Rtt = float64(ti.Rtt) / 1000
+	info.RttVar = float64(ti.Rttvar) / 1000
+	info.MinRtt = float64(ti.Min_rtt) / 1000
+	info.Retransmits = ti.Total_retrans
+	info.SegsOut = ti.Segs_out
+	return info, true
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+import "net"
+
+func readTcpInfo(conn net.Conn) (TcpConnInfo, bool) {
+	return TcpConnInfo{}, false
+}
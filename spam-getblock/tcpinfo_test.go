@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTcpInfoSummaryAdd(t *testing.T) {
+	var s TcpInfoSummary
+	for i := 0; i < NUM_TCP_CONNS+5; i++ {
+		// retransmits 0, 3, 6, ... wrapped, so the worst arrive out of order
+		s.add(TcpConnInfo{Remote: "peer", Rtt: 2, Retransmits: uint32(i * 3 % 31), SegsOut: 10})
+	}
+	if s.Connections != NUM_TCP_CONNS+5 || s.SegsOut != uint64(10*(NUM_TCP_CONNS+5)) {
+		t.Fatalf("connections %d, segs out %d", s.Connections, s.SegsOut)
+	}
+	var total uint64
+	for i := 0; i < NUM_TCP_CONNS+5; i++ {
+		total += uint64(i * 3 % 31)
+	}
+	if s.Retransmits != total {
+		t.Fatalf("retransmits %d, expected %d", s.Retransmits, total)
+	}
+	if len(s.Worst) != NUM_TCP_CONNS {
+		t.Fatalf("%d worst, expected %d", len(s.Worst), NUM_TCP_CONNS)
+	}
+	for i := 1; i < len(s.Worst); i++ {
+		if s.Worst[i].Retransmits > s.Worst[i-1].Retransmits {
+			t.Fatalf("worst not sorted at %d: %d after %d", i, s.Worst[i].Retransmits, s.Worst[i-1].Retransmits)
+		}
+	}
+	if s.Worst[0].Retransmits != 30 {
+		t.Fatalf("worst has %d retransmits, expected 30", s.Worst[0].Retransmits)
+	}
+	if s.rtt.Count != uint64(NUM_TCP_CONNS+5) || s.rtt.Max != 2*time.Millisecond {
+		t.Fatalf("rtt %s", s.rtt.String())
+	}
+}
+
+func TestTcpInfoSummaryAddFew(t *testing.T) {
+	var s TcpInfoSummary
+	for _, r := range []uint32{1, 5, 0, 5, 3} {
+		s.add(TcpConnInfo{Retransmits: r})
+	}
+	var got []uint32
+	for _, c := range s.Worst {
+		got = append(got, c.Retransmits)
+	}
+	if want := []uint32{5, 5, 3, 1, 0}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("worst %v, expected %v", got, want)
+	}
+}
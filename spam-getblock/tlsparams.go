@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// tls.VersionName is go 1.21
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func tlsVersionName(v uint16) string {
+	for name, version := range tlsVersions {
+		if version == v {
+			return "TLS" + name
+		}
+	}
+	return fmt.Sprintf("0x%04x", v)
+}
+
+// parsed by CheckTlsConfig; zero values leave go's defaults
+var (
+	tlsMinVersion   uint16
+	tlsMaxVersion   uint16
+	tlsCipherSuites []uint16
+)
+
+func CheckTlsConfig() error {
+	if TLS_MIN_VERSION == "" && TLS_MAX_VERSION == "" && TLS_CIPHERS == "" {
+		return nil
+	}
+	if PLAINTEXT {
+		return fmt.Errorf("-tls-min-version, -tls-max-version and -tls-ciphers need TLS, not -plaintext")
+	}
+	var ok bool
+	if TLS_MIN_VERSION != "" {
+		if tlsMinVersion, ok = tlsVersions[TLS_MIN_VERSION]; !ok {
+			return fmt.Errorf("bad -tls-min-version '%s', expected 1.0, 1.1, 1.2 or 1.3", TLS_MIN_VERSION)
+		}
+	}
+	if TLS_MAX_VERSION != "" {
+		if tlsMaxVersion, ok = tlsVersions[TLS_MAX_VERSION]; !ok {
+			return fmt.Errorf("bad -tls-max-version '%s', expected 1.0, 1.1, 1.2 or 1.3", TLS_MAX_VERSION)
+		}
+	}
+	if tlsMinVersion != 0 && tlsMaxVersion != 0 && tlsMinVersion > tlsMaxVersion {
+		return fmt.Errorf("-tls-min-version %s is above -tls-max-version %s", TLS_MIN_VERSION, TLS_MAX_VERSION)
+	}
+	if TLS_CIPHERS == "" {
+		return nil
+	}
+	if tlsMinVersion == tls.VersionTLS13 {
+		return fmt.Errorf("-tls-ciphers has no effect with -tls-min-version 1.3, go doesn't make TLS 1.3 suites configurable")
+	}
+	// insecure ones too, checking that a gateway refuses them is the point
+	suites := map[string]*tls.CipherSuite{}
+	for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		suites[s.Name] = s
+	}
+	for _, name := range strings.Split(TLS_CIPHERS, ",") {
+		name = strings.TrimSpace(name)
+		s, ok := suites[name]
+		if !ok {
+			return fmt.Errorf("bad -tls-ciphers, unknown suite '%s'", name)
+		}
+		if len(s.SupportedVersions) == 1 && s.SupportedVersions[0] == tls.VersionTLS13 {
+			return fmt.Errorf("bad -tls-ciphers, %s is a TLS 1.3 suite, which go doesn't make configurable", name)
+		}
+		tlsCipherSuites = append(tlsCipherSuites, s.ID)
+	}
+	return nil
+}
+
+// handshakes by what they negotiated, over every connection whether shared,
+// pooled or per request
+type TlsParamsSummary struct {
+	Version    string            `json:"version"`
+	Cipher     string            `json:"cipher"`
+	ALPN       string            `json:"alpn"`
+	Handshakes int               `json:"handshakes"`
+	Resumed    int               `json:"resumed"`
+	Handshake  PercentileSummary `json:"handshake"`
+	handshake  Histogram
+}
+
+var (
+	tlsParamsMutex sync.Mutex
+	tlsParams      = map[string]*TlsParamsSummary{}
+)
+
+// records what each successful handshake negotiated and how long it took
+type tlsParamsCreds struct {
+	credentials.TransportCredentials
+}
+
+func (c *tlsParamsCreds) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	start := time.Now()
+	conn, info, err := c.TransportCredentials.ClientHandshake(ctx, authority, conn)
+	if ti, ok := info.(credentials.TLSInfo); ok && err == nil {
+		recordTlsParams(ti.State, time.Since(start))
+	}
+	return conn, info, err
+}
+
+func (c *tlsParamsCreds) Clone() credentials.TransportCredentials {
+	return &tlsParamsCreds{c.TransportCredentials.Clone()}
+}
+
+func recordTlsParams(state tls.ConnectionState, took time.Duration) {
+	version := tlsVersionName(state.Version)
+	cipher := tls.CipherSuiteName(state.CipherSuite)
+	key := version + " " + cipher + " " + state.NegotiatedProtocol
+	tlsParamsMutex.Lock()
+	defer tlsParamsMutex.Unlock()
+	p, ok := tlsParams[key]
+	if !ok {
+		p = &TlsParamsSummary{Version: version, Cipher: cipher, ALPN: state.NegotiatedProtocol}
+		tlsParams[key] = p
+	}
+	p.Handshakes++
+	if state.DidResume {
+		p.Resumed++
+	}
+	p.handshake.Add(took)
+}
+
+func TlsParams() []TlsParamsSummary {
+	tlsParamsMutex.Lock()
+	defer tlsParamsMutex.Unlock()
+	params := make([]TlsParamsSummary, 0, len(tlsParams))
+	for _, p := range tlsParams {
+		s := *p
+		s.handshake.counts = append([]uint64(nil), p.handshake.counts...)
+		s.Handshake = p.handshake.Summary()
+		params = append(params, s)
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i].Handshakes > params[j].Handshakes })
+	return params
+}
+
+// what the gateway agreed to, to check its policy, and what each choice
+// cost in handshake time
+func PrintTlsParams() {
+	params := TlsParams()
+	if len(params) == 0 {
+		return
+	}
+	fmt.Println("TLS:")
+	for _, p := range params {
+		fmt.Printf("\t%s %s alpn=%s: %d handshakes, %d resumed, handshake: %s\n",
+			p.Version, p.Cipher, p.ALPN, p.Handshakes, p.Resumed, p.handshake.String())
+	}
+}
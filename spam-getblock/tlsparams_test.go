@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/tls"
+	"reflect"
+	"testing"
+)
+
+func TestCheckTlsConfig(t *testing.T) {
+	defer func(min, max, ciphers string, plaintext bool) {
+		TLS_MIN_VERSION, TLS_MAX_VERSION, TLS_CIPHERS, PLAINTEXT = min, max, ciphers, plaintext
+		tlsMinVersion, tlsMaxVersion, tlsCipherSuites = 0, 0, nil
+	}(TLS_MIN_VERSION, TLS_MAX_VERSION, TLS_CIPHERS, PLAINTEXT)
+
+	for _, tt := range []struct {
+		name      string
+		min, max  string
+		ciphers   string
+		plaintext bool
+		ok        bool
+		wantMin   uint16
+		wantMax   uint16
+		wantSuite []uint16
+	}{
+		{name: "unset", ok: true},
+		{name: "unset with plaintext", plaintext: true, ok: true},
+		{name: "min", min: "1.2", ok: true, wantMin: tls.VersionTLS12},
+		{name: "range", min: "1.2", max: "1.3", ok: true, wantMin: tls.VersionTLS12, wantMax: tls.VersionTLS13},
+		{name: "pinned", min: "1.3", max: "1.3", ok: true, wantMin: tls.VersionTLS13, wantMax: tls.VersionTLS13},
+		{name: "inverted", min: "1.3", max: "1.2"},
+		{name: "bad version", min: "1.4"},
+		{name: "v prefix", max: "v1.2"},
+		{name: "plaintext", min: "1.2", plaintext: true},
+		{name: "ciphers", max: "1.2", ciphers: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, TLS_RSA_WITH_AES_128_CBC_SHA", ok: true,
+			wantMax:   tls.VersionTLS12,
+			wantSuite: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_AES_128_CBC_SHA}},
+		{name: "insecure cipher", ciphers: "TLS_RSA_WITH_RC4_128_SHA", ok: true, wantSuite: []uint16{tls.TLS_RSA_WITH_RC4_128_SHA}},
+		{name: "unknown cipher", ciphers: "TLS_NULL"},
+		{name: "tls 1.3 cipher", ciphers: "TLS_AES_128_GCM_SHA256"},
+		{name: "ciphers with only 1.3", min: "1.3", ciphers: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			TLS_MIN_VERSION, TLS_MAX_VERSION, TLS_CIPHERS, PLAINTEXT = tt.min, tt.max, tt.ciphers, tt.plaintext
+			tlsMinVersion, tlsMaxVersion, tlsCipherSuites = 0, 0, nil
+			err := CheckTlsConfig()
+			if (err == nil) != tt.ok {
+				t.Fatalf("error %v", err)
+			}
+			if !tt.ok {
+				return
+			}
+			if tlsMinVersion != tt.wantMin || tlsMaxVersion != tt.wantMax {
+				t.Fatalf("versions %s-%s, expected %s-%s", tlsVersionName(tlsMinVersion), tlsVersionName(tlsMaxVersion),
+					tlsVersionName(tt.wantMin), tlsVersionName(tt.wantMax))
+			}
+			if !reflect.DeepEqual(tlsCipherSuites, tt.wantSuite) {
+				t.Fatalf("suites %v, expected %v", tlsCipherSuites, tt.wantSuite)
+			}
+		})
+	}
+}
+
+func TestTlsVersionName(t *testing.T) {
+	for _, tt := range []struct {
+		v    uint16
+		want string
+	}{
+		{tls.VersionTLS10, "TLS1.0"},
+		{tls.VersionTLS13, "TLS1.3"},
+		{0x0300, "0x0300"},
+	} {
+		if got := tlsVersionName(tt.v); got != tt.want {
+			t.Errorf("%#04x: got %q, expected %q", tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rolling window the tui computes throughput and percentiles over
+const TUI_WINDOW = 10 * time.Second
+
+// errors kept for the tui's error log
+const TUI_ERROR_LINES = 10
+
+type tuiSample struct {
+	At     time.Time
+	Total  time.Duration
+	Failed bool
+}
+
+var tui struct {
+	sync.Mutex
+	start   time.Time
+	done    int
+	errors  int
+	samples []tuiSample
+	log     []string
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// takes over the terminal (alternate screen) and redraws twice a second
+// until StopTui
+func StartTui() {
+	tui.start = time.Now()
+	tui.stop = make(chan struct{})
+	tui.stopped = make(chan struct{})
+	statusSinks = append(statusSinks, tuiObserve)
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	go func() {
+		defer close(tui.stopped)
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				drawTui()
+			case <-tui.stop:
+				return
+			}
+		}
+	}()
+}
+
+// gives the terminal back so the final summary prints normally
+func StopTui() {
+	close(tui.stop)
+	<-tui.stopped
+	fmt.Print("\x1b[?25h\x1b[?1049l")
+}
+
+func tuiObserve(s *ThreadStatus) {
+	tui.Lock()
+	defer tui.Unlock()
+	tui.done++
+	sample := tuiSample{At: time.Now(), Total: s.times.Total(), Failed: s.err != nil}
+	tui.samples = append(tui.samples, sample)
+	if s.err != nil {
+		tui.errors++
+		line := fmt.Sprintf("%s %d: %s", sample.At.Format("15:04:05"), s.ID, s.err)
+		tui.log = append(tui.log, line)
+		if len(tui.log) > TUI_ERROR_LINES {
+			tui.log = tui.log[len(tui.log)-TUI_ERROR_LINES:]
+		}
+	}
+}
+
+func drawTui() {
+	tui.Lock()
+	now := time.Now()
+	cutoff := now.Add(-TUI_WINDOW)
+	i := sort.Search(len(tui.samples), func(i int) bool { return tui.samples[i].At.After(cutoff) })
+	tui.samples = tui.samples[i:]
+	window := make([]time.Duration, len(tui.samples))
+	windowErrors := 0
+	for i, s := range tui.samples {
+		window[i] = s.Total
+		if s.Failed {
+			windowErrors++
+		}
+	}
+	done, errors := tui.done, tui.errors
+	log := append([]string(nil), tui.log...)
+	tui.Unlock()
+
+	sort.Slice(window, func(i, j int) bool { return window[i] < window[j] })
+	percentile := func(q float64) time.Duration {
+		if len(window) == 0 {
+			return 0
+		}
+		return window[int(q*float64(len(window)-1))]
+	}
+	span := TUI_WINDOW
+	if elapsed := now.Sub(tui.start); elapsed < span {
+		span = elapsed
+	}
+	errorRate := 0.0
+	if len(window) != 0 {
+		errorRate = 100 * float64(windowErrors) / float64(len(window))
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	fmt.Fprintf(&b, "%s  %s  %s  elapsed %s\n\n", CALL, URL, RUNTIME, now.Sub(tui.start).Round(time.Second))
+	fmt.Fprintf(&b, "done        %d / %d\n", done, NUM_REQUESTS)
+	fmt.Fprintf(&b, "in flight   %d\n", InFlight())
+	fmt.Fprintf(&b, "throughput  %.1f /s\n", float64(len(window))/span.Seconds())
+	fmt.Fprintf(&b, "p50         %s\n", percentile(0.5).Round(time.Microsecond))
+	fmt.Fprintf(&b, "p95         %s\n", percentile(0.95).Round(time.Microsecond))
+	fmt.Fprintf(&b, "p99         %s\n", percentile(0.99).Round(time.Microsecond))
+	fmt.Fprintf(&b, "error rate  %.1f%% (%d total)\n\n", errorRate, errors)
+	fmt.Fprintf(&b, "last %s; recent errors:\n", TUI_WINDOW)
+	for _, line := range log {
+		b.WriteString("  " + line + "\n")
+	}
+	os.Stdout.WriteString(b.String())
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	oasisGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	runtime "github.com/oasisprotocol/oasis-core/go/runtime/client/api"
+	"github.com/oasisprotocol/oasis-core/go/runtime/transaction"
+	storage "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+// tx hashes harvested by HarvestTxHashes, keyed by round
+var harvestedTxs = map[uint64][]hash.Hash{}
+var harvestedRounds []uint64
+
+// fetches the transactions of HARVEST_ROUNDS random rounds and remembers their hashes
+func HarvestTxHashes(ctx context.Context) error {
+	conn, err := oasisGrpc.Dial(URL, dialOpts...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// another runtime's rounds with -all-runtimes
+	harvestedTxs = map[uint64][]hash.Hash{}
+	harvestedRounds = nil
+	client := runtime.NewRuntimeClient(conn)
+	num_txs := 0
+	for i := 0; i < HARVEST_ROUNDS; i++ {
+		round := RandomSapphireHeight()
+		if _, ok := harvestedTxs[round]; ok {
+			continue
+		}
+		txs, err := client.GetTransactions(ctx, &runtime.GetTransactionsRequest{
+			RuntimeID: RUNTIME_ID,
+			Round:     round,
+		})
+		if err != nil {
+			return fmt.Errorf("round %d: %w", round, err)
+		}
+		if len(txs) == 0 {
+			continue
+		}
+		for _, tx := range txs {
+			harvestedTxs[round] = append(harvestedTxs[round], hash.NewFromBytes(tx))
+		}
+		harvestedRounds = append(harvestedRounds, round)
+		num_txs += len(txs)
+	}
+	if num_txs == 0 {
+		return fmt.Errorf("no transactions found in %d rounds", HARVEST_ROUNDS)
+	}
+	fmt.Println("Harvested", num_txs, "tx hashes from", len(harvestedRounds), "rounds")
+	return nil
+}
+
+func RandomHarvestedRound() uint64 {
+	return harvestedRounds[rand.Intn(len(harvestedRounds))]
+}
+
+// looks up a single harvested transaction by hash in the round's IO tree; the
+// node has no tx hash index, so this is what a point lookup costs
+func GetTxByHash(ctx context.Context, round uint64) ThreadStatus {
+	status := ThreadStatus{ID: round, times: ApiTimes{}}
+	// -rounds-file and -from/-to pick rounds of their own
+	hashes := harvestedTxs[round]
+	if len(hashes) == 0 {
+		status.err = fmt.Errorf("round %d has no harvested tx hashes", round)
+		return status
+	}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	start := time.Now()
+	block, err := runtime.NewRuntimeClient(conn).GetBlock(ctx, &runtime.GetBlockRequest{
+		RuntimeID: RUNTIME_ID,
+		Round:     round,
+	})
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.times.GetBlock = time.Since(start)
+
+	txHash := hashes[rand.Intn(len(hashes))]
+	tree := transaction.NewTree(storage.NewStorageClient(conn), block.Header.StorageRoots()[0])
+	defer tree.Close()
+
+	start = time.Now()
+	tx, err := tree.GetTransaction(ctx, txHash)
+	status.times.Query = time.Since(start)
+	if err != nil {
+		status.err = fmt.Errorf("tx %s: %w", txHash, err)
+		return status
+	}
+	status.msg = fmt.Sprintf("Round: %d, TxHash: %s, InputSize: %d, OutputSize: %d", round, txHash, len(tx.Input), len(tx.Output))
+	return status
+}
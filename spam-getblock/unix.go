@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// a co-located node's socket, e.g. unix:/node/data/internal.sock, which
+// grpc dials itself and which takes no TLS
+func isUnixTarget(endpoint string) bool {
+	return strings.HasPrefix(endpoint, "unix:")
+}
+
+// the transport credentials follow the first -url, so the rest have to be
+// the same kind
+func CheckUnixTargets() error {
+	if !isUnixTarget(URL) {
+		for _, u := range URLS {
+			if isUnixTarget(u) {
+				return fmt.Errorf("-url %s: unix sockets and network endpoints can't be mixed", u)
+			}
+		}
+		return nil
+	}
+	for _, u := range URLS {
+		if !isUnixTarget(u) {
+			return fmt.Errorf("-url %s: unix sockets and network endpoints can't be mixed", u)
+		}
+	}
+	if RESOLVE_ALL {
+		return fmt.Errorf("-resolve-all needs host:port urls, not unix sockets")
+	}
+	return nil
+}
+
+// for byteCounter.dial, which grpc hands unix:path or unix://path
+func dialUnix(ctx context.Context, addr string) (net.Conn, error) {
+	path := strings.TrimPrefix(strings.TrimPrefix(addr, "unix://"), "unix:")
+	return (&net.Dialer{}).DialContext(ctx, "unix", path)
+}
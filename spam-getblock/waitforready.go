@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+)
+
+// -wait-for-ready and -backoff-* as dial options. without wait-for-ready an
+// rpc fails as soon as its connection is in transient failure; with it the
+// rpc queues until the connection is back or its deadline passes
+func readinessDialOpts() []grpc.DialOption {
+	opts := []grpc.DialOption{grpc.WithConnectParams(grpc.ConnectParams{
+		Backoff: backoff.Config{
+			BaseDelay:  BACKOFF_BASE_DELAY,
+			Multiplier: BACKOFF_MULTIPLIER,
+			Jitter:     BACKOFF_JITTER,
+			MaxDelay:   BACKOFF_MAX_DELAY,
+		},
+		MinConnectTimeout: MIN_CONNECT_TIMEOUT,
+	})}
+	if WAIT_FOR_READY {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.WaitForReady(true)))
+	}
+	return opts
+}
+
+// time the request's rpcs spent waiting for a ready connection, and the rest
+// of the request; only touched by the goroutine collecting statuses
+var (
+	queued    Histogram
+	notQueued Histogram
+)
+
+func RecordQueued(s *ThreadStatus) {
+	queued.Add(s.queued)
+	notQueued.Add(s.times.Total() - s.queued)
+}
+
+// for a fresh per-request connection the wait is mostly its setup, see
+// PrintConnTimes
+func PrintQueued() {
+	if queued.Max == 0 {
+		return
+	}
+	fmt.Printf("Queued (not ready): %s\n", queued.String())
+	fmt.Printf("Total without queued: %s\n", notQueued.String())
+}
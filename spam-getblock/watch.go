@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	oasisGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+)
+
+// running min/mean/max/stddev of a series of durations
+type DurationStats struct {
+	Count int
+	Min   time.Duration
+	Max   time.Duration
+	sum   float64
+	sumSq float64
+}
+
+func (s *DurationStats) Add(d time.Duration) {
+	if s.Count == 0 || d < s.Min {
+		s.Min = d
+	}
+	if s.Count == 0 || d > s.Max {
+		s.Max = d
+	}
+	s.Count++
+	s.sum += float64(d)
+	s.sumSq += float64(d) * float64(d)
+}
+
+func (s *DurationStats) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return time.Duration(s.sum / float64(s.Count))
+}
+
+func (s *DurationStats) StdDev() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	mean := s.sum / float64(s.Count)
+	return time.Duration(math.Sqrt(math.Max(0, s.sumSq/float64(s.Count)-mean*mean)))
+}
+
+func (s *DurationStats) String() string {
+	return fmt.Sprintf("min: %s, mean: %s, max: %s, stddev: %s", s.Min, s.Mean(), s.Max, s.StdDev())
+}
+
+// fetches the consensus blocks [from, to) that a stream skipped, returning
+// how many were fetched
+func BackfillConsensus(ctx context.Context, client consensus.ClientBackend, from, to int64) (int, error) {
+	n := 0
+	for height := from; height < to; height++ {
+		if _, err := client.GetBlock(ctx, height); err != nil {
+			return n, fmt.Errorf("backfill height %d: %w", height, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// subscribes to consensus blocks for DURATION and measures how long after
+// their timestamp they arrive, i.e. propagation rather than request latency.
+// a dropped stream is resubscribed and the skipped heights backfilled with
+// GetBlock, like a streaming indexer would
+func RunWatch(ctx context.Context) error {
+	conn, err := oasisGrpc.Dial(URL, dialOpts...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	WatchState(conn, "watch")
+
+	ctx, cancel := context.WithTimeout(ctx, DURATION)
+	defer cancel()
+	client := consensus.NewConsensusClient(conn)
+
+	var delay, interArrival, recovery DurationStats
+	var lastReceived, droppedAt time.Time
+	var lastHeight int64
+	num_drops, num_missed := 0, 0
+	for ctx.Err() == nil {
+		blocks, sub, err := client.WatchBlocks(ctx)
+		if err != nil {
+			if lastHeight == 0 {
+				return err
+			}
+			fmt.Println("Resubscribe error:", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		for block := range blocks {
+			received := time.Now()
+			if lastHeight != 0 && block.Height > lastHeight+1 {
+				n, err := BackfillConsensus(ctx, client, lastHeight+1, block.Height)
+				num_missed += n
+				fmt.Printf("Gap: %d-%d, Backfilled: %d\n", lastHeight+1, block.Height-1, n)
+				if err != nil {
+					fmt.Println("Backfill error:", err)
+				}
+			}
+			if !droppedAt.IsZero() {
+				recovery.Add(time.Since(droppedAt))
+				fmt.Printf("Recovered after: %s\n", time.Since(droppedAt))
+				droppedAt = time.Time{}
+			}
+			if block.Height <= lastHeight {
+				// resent on resubscription
+				continue
+			}
+			lastHeight = block.Height
+
+			d := received.Sub(block.Time)
+			delay.Add(d)
+			msg := fmt.Sprintf("Height: %d, Delay: %s", block.Height, d)
+			if !lastReceived.IsZero() {
+				interArrival.Add(received.Sub(lastReceived))
+				msg += fmt.Sprintf(", InterArrival: %s", received.Sub(lastReceived))
+			}
+			lastReceived = received
+			fmt.Println(msg)
+		}
+		sub.Close()
+		if ctx.Err() == nil {
+			num_drops++
+			droppedAt = time.Now()
+			lastReceived = time.Time{}
+			fmt.Println("Stream dropped after height", lastHeight)
+		}
+	}
+
+	fmt.Println("Blocks:", delay.Count)
+	fmt.Println("Delay:", delay.String())
+	fmt.Println("Inter-arrival:", interArrival.String())
+	fmt.Println("Jitter:", interArrival.StdDev())
+	fmt.Println("Drops:", num_drops, "Missed:", num_missed)
+	fmt.Println("Recovery:", recovery.String())
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	runtime "github.com/oasisprotocol/oasis-core/go/runtime/client/api"
+)
+
+type web3Request struct {
+	JsonRpc string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type web3Response struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type web3Block struct {
+	Hash         string            `json:"hash"`
+	Transactions []json.RawMessage `json:"transactions"`
+}
+
+func CheckWeb3Url(ctx context.Context) error {
+	if WEB3_URL == "" {
+		return fmt.Errorf("web3-compare needs -web3-url, e.g. https://sapphire.oasis.io")
+	}
+	return nil
+}
+
+// one json-rpc call against the web3 gateway, decoding result into v
+func Web3Call(ctx context.Context, v interface{}, method string, params ...interface{}) error {
+	body, err := json.Marshal(web3Request{JsonRpc: "2.0", ID: 1, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, WEB3_URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: http %s", method, rsp.Status)
+	}
+	var result web3Response
+	if err := json.NewDecoder(rsp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if result.Error != nil {
+		return fmt.Errorf("%s: %d %s", method, result.Error.Code, result.Error.Message)
+	}
+	if string(result.Result) == "null" {
+		return fmt.Errorf("%s: no result", method)
+	}
+	return json.Unmarshal(result.Result, v)
+}
+
+// the same round over grpc (GetBlock, GetEvents) and over the web3 gateway
+// (eth_getBlockByNumber, eth_getLogs); sapphire evm block numbers are
+// runtime rounds
+func CompareWeb3(ctx context.Context, height uint64) ThreadStatus {
+	status := ThreadStatus{ID: height, times: ApiTimes{}}
+	conn, err := Connect(ctx, &status)
+	if err != nil {
+		status.err = err
+		return status
+	}
+	defer Release(conn)
+
+	client := runtime.NewRuntimeClient(conn)
+	start := time.Now()
+	_, err = client.GetBlock(ctx, &runtime.GetBlockRequest{
+		RuntimeID: RUNTIME_ID,
+		Round:     height,
+	})
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.times.GetBlock = time.Since(start)
+
+	start = time.Now()
+	txs, err := client.GetTransactions(ctx, &runtime.GetTransactionsRequest{
+		RuntimeID: RUNTIME_ID,
+		Round:     height,
+	})
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.times.GetTransactions = time.Since(start)
+
+	start = time.Now()
+	events, err := client.GetEvents(ctx, &runtime.GetEventsRequest{
+		RuntimeID: RUNTIME_ID,
+		Round:     height,
+	})
+	if err != nil {
+		status.err = err
+		return status
+	}
+	status.times.GetEvents = time.Since(start)
+
+	number := fmt.Sprintf("0x%x", height)
+	var block web3Block
+	start = time.Now()
+	if err := Web3Call(ctx, &block, "eth_getBlockByNumber", number, false); err != nil {
+		status.err = err
+		return status
+	}
+	status.times.Web3GetBlock = time.Since(start)
+
+	var logs []json.RawMessage
+	start = time.Now()
+	if err := Web3Call(ctx, &logs, "eth_getLogs", map[string]string{"fromBlock": number, "toBlock": number}); err != nil {
+		status.err = err
+		return status
+	}
+	status.times.Web3GetLogs = time.Since(start)
+
+	grpcTime := status.times.GetBlock + status.times.GetTransactions + status.times.GetEvents
+	web3Time := status.times.Web3GetBlock + status.times.Web3GetLogs
+	status.msg = fmt.Sprintf("Round: %d, gRPC: %s (txs: %d, events: %d), Web3: %s (txs: %d, logs: %d, hash: %s)",
+		height, grpcTime, len(txs), len(events), web3Time, len(block.Transactions), len(logs), block.Hash)
+	return status
+}